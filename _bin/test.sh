@@ -8,7 +8,7 @@ go get -u github.com/golangci/golangci-lint/cmd/golangci-lint
 golangci-lint run
 
 # tests & coverage
-go test -coverprofile=coverage.out -v ./...
+go test -race -coverprofile=coverage.out -v ./...
 go tool cover -func=coverage.out
 
 # clean after self
@@ -23,6 +23,7 @@ var (
 	lshSources   = lshCmd.String("s", "", "List of sources separated by comma.")
 	lshNumHashes = lshCmd.Int("hashes", 0, "Number of hash functions.")
 	lshNumBands  = lshCmd.Int("bands", 0, "Number of bands.")
+	lshThreshold = lshCmd.Float64("threshold", 0, "Jaccard similarity threshold, chooses number of bands instead of \"bands\".")
 
 	// similarity command
 	simCmd       = flag.NewFlagSet("sim", flag.ExitOnError)
@@ -94,13 +95,20 @@ func getShingles(source string, doKShingle bool) []string {
 	return lsh.Shingle(textLines)
 }
 
-func shingleSets(sourcesList []string, doKShingle bool) ([][]string, int) {
+func shingleSets(sourcesList []string, doKShingle bool) ([]string, [][]string, int) {
 	fmt.Printf("\nshingling %d sources:\n", len(sourcesList))
 
+	ids := make([]string, 0)
 	shingleSets := make([][]string, 0)
-	var k int
+	seen := make(map[string]bool)
 	var totalSize int
 	for _, s := range sourcesList {
+		// sources are used as IDs, hence must be unique
+		if seen[s] {
+			fmt.Printf("---> skipping %s: duplicate\n", s)
+			continue
+		}
+		seen[s] = true
 		shingles := getShingles(s, doKShingle)
 		// skip empty
 		if len(shingles) == 0 {
@@ -108,11 +116,14 @@ func shingleSets(sourcesList []string, doKShingle bool) ([][]string, int) {
 			continue
 		}
 		totalSize += len(shingles)
+		ids = append(ids, s)
 		shingleSets = append(shingleSets, shingles)
-		fmt.Printf("[%d]: %s - %.150s\n", k, s, shingles[0])
-		k++
+		fmt.Printf("%s - %.150s\n", s, shingles[0])
 	}
-	return shingleSets, totalSize / len(shingleSets)
+	if len(shingleSets) == 0 {
+		return ids, shingleSets, 0
+	}
+	return ids, shingleSets, totalSize / len(shingleSets)
 }
 
 func doShingles(cmd *flag.FlagSet) {
@@ -124,7 +135,7 @@ func doShingles(cmd *flag.FlagSet) {
 func doLSH(cmd *flag.FlagSet) {
 	parseCommand(cmd)
 
-	shingleSets, avgSize := shingleSets(toSourceList(*lshSources), false)
+	ids, shingleSets, avgSize := shingleSets(toSourceList(*lshSources), false)
 	if len(shingleSets) < 2 {
 		fmt.Printf("nothing to compare, got %d shingle set(s)\n", len(shingleSets))
 		os.Exit(0)
@@ -139,20 +150,34 @@ func doLSH(cmd *flag.FlagSet) {
 		numHashes = lsh.SuggestHashNum(avgSize)
 	}
 
-	numBands := *lshNumBands
-	if numBands == 0 {
-		numBands = numHashes / 5
-	}
-
-	if numBands == 0 {
-		numBands = 1
-	}
-
 	fmt.Printf("\napplying %d hash functions\n", numHashes)
 	signatureMatrix := lsh.Minhash(shingleSets, numHashes)
 
-	fmt.Printf("\ndistributing into %d bands\n", numBands)
-	bandBuckets := lsh.LSH(signatureMatrix, numBands)
+	var bandBuckets *lsh.BandBuckets
+	var err error
+	if *lshThreshold != 0 {
+		fmt.Printf("\ndistributing into bands for threshold %.2f\n", *lshThreshold)
+		bandBuckets, err = lsh.LSHWithThreshold(signatureMatrix, *lshThreshold, lsh.IDs(ids))
+	} else {
+		numBands := *lshNumBands
+		if numBands == 0 {
+			numBands = numHashes / 5
+		}
+
+		if numBands == 0 {
+			numBands = 1
+		}
+
+		fmt.Printf("\ndistributing into %d bands\n", numBands)
+		bandBuckets, err = lsh.LSH(signatureMatrix, numBands, lsh.IDs(ids))
+	}
+	if err != nil {
+		fmt.Printf("can't distribute into bands: %v\n", err)
+		os.Exit(5)
+	}
+	if unused := bandBuckets.UnusedHashes(); len(unused) > 0 {
+		fmt.Printf("\n%d hash function(s) don't fit into bands and are unused\n", len(unused))
+	}
 	candidatePairs := bandBuckets.FindCandidatePairs()
 
 	fmt.Printf("\nfound %d candidate pair(s)\n", len(candidatePairs.Index))
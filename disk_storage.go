@@ -0,0 +1,240 @@
+package lsh
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	metaBucket       = []byte("meta")
+	signaturesBucket = []byte("signatures")
+	bandsBucket      = []byte("bands")
+	propertiesBucket = []byte("properties")
+
+	bandsKey = []byte("bands")
+	rowsKey  = []byte("rows")
+)
+
+// DiskStorage keeps signatures and buckets in an embedded key-value file,
+// pages of the file are memory mapped and read on demand,
+// so index can be larger than available RAM.
+//
+// Entries of a band are stored under keys made of the band key followed by the document ID,
+// band keys have the same length within a band, so a bucket is a range of keys with the same prefix.
+type DiskStorage struct {
+	db  *bolt.DB
+	len int
+}
+
+// OpenDiskStorage opens storage in the file at the given path, the file is created if it doesn't exist.
+func OpenDiskStorage(path string) (*DiskStorage, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, fmt.Errorf("can't open disk storage %s: %v", path, err)
+	}
+	ds := &DiskStorage{db: db}
+	// documents are counted right away, so Len is valid before Init
+	err = db.View(func(tx *bolt.Tx) error {
+		if sigs := tx.Bucket(signaturesBucket); sigs != nil {
+			ds.len = sigs.Stats().KeyN
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("can't open disk storage %s: %v", path, err)
+	}
+	return ds, nil
+}
+
+// Init implements Storage.
+func (ds *DiskStorage) Init(bands, rows int) error {
+	return ds.db.Update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+		if err := initMeta(meta, bandsKey, bands); err != nil {
+			return err
+		}
+		if err := initMeta(meta, rowsKey, rows); err != nil {
+			return err
+		}
+
+		if _, err := tx.CreateBucketIfNotExists(signaturesBucket); err != nil {
+			return err
+		}
+
+		bandsRoot, err := tx.CreateBucketIfNotExists(bandsBucket)
+		if err != nil {
+			return err
+		}
+		for b := 0; b < bands; b++ {
+			if _, err := bandsRoot.CreateBucketIfNotExists(bandName(b)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// initMeta stores the given configuration value, or verifies that it matches already stored one.
+func initMeta(meta *bolt.Bucket, key []byte, value int) error {
+	if stored := meta.Get(key); stored != nil {
+		if v := int(binary.LittleEndian.Uint64(stored)); v != value {
+			return fmt.Errorf("disk storage holds index with %s %d, got %d", key, v, value)
+		}
+		return nil
+	}
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, uint64(value))
+	return meta.Put(key, b)
+}
+
+// Apply implements Storage.
+func (ds *DiskStorage) Apply(updates []*StorageUpdate) error {
+	var delta int
+	err := ds.db.Update(func(tx *bolt.Tx) error {
+		delta = 0
+		sigs := tx.Bucket(signaturesBucket)
+		bandsRoot := tx.Bucket(bandsBucket)
+		for _, u := range updates {
+			id := []byte(u.ID)
+			existed := sigs.Get(id) != nil
+			for b, key := range u.OldKeys {
+				if err := bandsRoot.Bucket(bandName(b)).Delete(bandEntry(key, u.ID)); err != nil {
+					return err
+				}
+			}
+			if u.Signature == nil {
+				if existed {
+					delta--
+				}
+				if err := sigs.Delete(id); err != nil {
+					return err
+				}
+				continue
+			}
+			if !existed {
+				delta++
+			}
+			if err := sigs.Put(id, encodeSignature(u.Signature)); err != nil {
+				return err
+			}
+			for b, key := range u.Keys {
+				if err := bandsRoot.Bucket(bandName(b)).Put(bandEntry(key, u.ID), []byte{}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		ds.len += delta
+	}
+	return err
+}
+
+// Get implements Storage.
+func (ds *DiskStorage) Get(id string) (Signature, bool, error) {
+	var sig Signature
+	err := ds.db.View(func(tx *bolt.Tx) error {
+		// values are only valid during transaction, decoding copies them
+		if v := tx.Bucket(signaturesBucket).Get([]byte(id)); v != nil {
+			sig = decodeSignature(v)
+		}
+		return nil
+	})
+	return sig, sig != nil, err
+}
+
+// Bucket implements Storage.
+func (ds *DiskStorage) Bucket(band int, key string) ([]string, error) {
+	ids := make([]string, 0)
+	err := ds.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bandsBucket).Bucket(bandName(band))
+		if bucket == nil {
+			return fmt.Errorf("disk storage has no band %d", band)
+		}
+		prefix := []byte(key)
+		c := bucket.Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			ids = append(ids, string(k[len(prefix):]))
+		}
+		return nil
+	})
+	return ids, err
+}
+
+// ForEach implements Storage.
+func (ds *DiskStorage) ForEach(fn func(id string, sig Signature) error) error {
+	return ds.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(signaturesBucket).ForEach(func(k, v []byte) error {
+			return fn(string(k), decodeSignature(v))
+		})
+	})
+}
+
+// Len implements Storage.
+func (ds *DiskStorage) Len() int {
+	return ds.len
+}
+
+// Property implements Storage.
+func (ds *DiskStorage) Property(key string) ([]byte, error) {
+	var value []byte
+	err := ds.db.View(func(tx *bolt.Tx) error {
+		// bucket doesn't exist until the first property is set
+		if properties := tx.Bucket(propertiesBucket); properties != nil {
+			if v := properties.Get([]byte(key)); v != nil {
+				value = append([]byte(nil), v...)
+			}
+		}
+		return nil
+	})
+	return value, err
+}
+
+// SetProperty implements Storage.
+func (ds *DiskStorage) SetProperty(key string, value []byte) error {
+	return ds.db.Update(func(tx *bolt.Tx) error {
+		properties, err := tx.CreateBucketIfNotExists(propertiesBucket)
+		if err != nil {
+			return err
+		}
+		return properties.Put([]byte(key), value)
+	})
+}
+
+// Close implements Storage.
+func (ds *DiskStorage) Close() error {
+	return ds.db.Close()
+}
+
+func bandName(band int) []byte {
+	return []byte(strconv.Itoa(band))
+}
+
+func bandEntry(key, id string) []byte {
+	return []byte(key + id)
+}
+
+func encodeSignature(sig Signature) []byte {
+	b := make([]byte, 8*len(sig))
+	for i, v := range sig {
+		binary.LittleEndian.PutUint64(b[8*i:], v)
+	}
+	return b
+}
+
+func decodeSignature(b []byte) Signature {
+	sig := make(Signature, len(b)/8)
+	for i := range sig {
+		sig[i] = binary.LittleEndian.Uint64(b[8*i:])
+	}
+	return sig
+}
@@ -0,0 +1,201 @@
+package lsh
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tempStoragePath(t testing.TB) (string, func()) {
+	dir, err := ioutil.TempDir("", "lsh")
+	assert.Nil(t, err)
+	return filepath.Join(dir, "index.db"), func() { os.RemoveAll(dir) }
+}
+
+func Test_DiskStorage(t *testing.T) {
+	path, cleanup := tempStoragePath(t)
+	defer cleanup()
+	hashers := GenerateHashersSeeded(20, 1)
+
+	storage, err := OpenDiskStorage(path)
+	assert.Nil(t, err)
+	idx, err := NewLSHIndexWithStorage(10, 2, storage)
+	assert.Nil(t, err)
+
+	assert.Nil(t, idx.Add("a", NewSignature(aShingles, hashers)))
+	assert.Nil(t, idx.Add("a copy", NewSignature(aShingles, hashers)))
+	assert.Nil(t, idx.Add("b", NewSignature(bShingles, hashers)))
+	assert.Equal(t, 3, idx.Len())
+
+	found, err := idx.Query(NewSignature(aShingles, hashers))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "a copy"}, found)
+
+	removed, err := idx.Remove("a copy")
+	assert.Nil(t, err)
+	assert.True(t, removed)
+	assert.Nil(t, idx.Close())
+
+	// reopen
+	storage, err = OpenDiskStorage(path)
+	assert.Nil(t, err)
+	idx, err = NewLSHIndexWithStorage(10, 2, storage)
+	assert.Nil(t, err)
+	defer idx.Close()
+	assert.Equal(t, 2, idx.Len())
+
+	sig, ok, err := idx.Signature("b")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, NewSignature(bShingles, hashers), sig)
+
+	found, err = idx.Query(NewSignature(aShingles, hashers))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a"}, found)
+}
+
+func Test_LSHIndex_AddAll(t *testing.T) {
+	path, cleanup := tempStoragePath(t)
+	defer cleanup()
+	hashers := GenerateHashersSeeded(20, 1)
+
+	disk, err := OpenDiskStorage(path)
+	assert.Nil(t, err)
+	defer disk.Close()
+
+	for _, storage := range []Storage{NewMemoryStorage(), disk} {
+		idx, err := NewLSHIndexWithStorage(10, 2, storage)
+		assert.Nil(t, err)
+
+		// the latter signature of the same document wins
+		aSig, bSig := NewSignature(aShingles, hashers), NewSignature(bShingles, hashers)
+		assert.Nil(t, idx.AddAll([]string{"doc", "other", "doc"}, []Signature{aSig, aSig, bSig}))
+		assert.Equal(t, 2, idx.Len())
+
+		found, err := idx.Query(aSig)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"other"}, found)
+		found, err = idx.Query(bSig)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"doc"}, found)
+
+		removed, err := idx.Remove("doc")
+		assert.Nil(t, err)
+		assert.True(t, removed)
+		assert.Equal(t, 1, idx.Len())
+		found, err = idx.Query(bSig)
+		assert.Nil(t, err)
+		assert.Empty(t, found)
+
+		assert.NotNil(t, idx.AddAll([]string{"a"}, nil))
+	}
+}
+
+func Test_DiskStorage_mismatch(t *testing.T) {
+	path, cleanup := tempStoragePath(t)
+	defer cleanup()
+
+	storage, err := OpenDiskStorage(path)
+	assert.Nil(t, err)
+	assert.Nil(t, storage.Init(10, 2))
+	assert.Nil(t, storage.Close())
+
+	storage, err = OpenDiskStorage(path)
+	assert.Nil(t, err)
+	defer storage.Close()
+	assert.NotNil(t, storage.Init(5, 4))
+}
+
+func Test_Search_DiskStorage_Hashers(t *testing.T) {
+	path, cleanup := tempStoragePath(t)
+	defer cleanup()
+
+	storage, err := OpenDiskStorage(path)
+	assert.Nil(t, err)
+	search, err := NewSearch(WithStorage(storage), Hashers(GenerateHashersSeeded(50, 1)), BandsNum(10))
+	assert.Nil(t, err)
+	assert.Nil(t, search.Add("jim", Shingle([]string{jimText})))
+	assert.Nil(t, search.Close())
+
+	// hashers are restored from storage
+	storage, err = OpenDiskStorage(path)
+	assert.Nil(t, err)
+	search, err = NewSearch(WithStorage(storage), BandsNum(10))
+	assert.Nil(t, err)
+	for i, hasher := range GenerateHashersSeeded(50, 1) {
+		assert.Equal(t, hasher.String(), search.hashers[i].String())
+	}
+	results := find(t, search, jimText)
+	assert.Len(t, results, 1)
+	assert.Nil(t, search.Close())
+
+	// signatures computed by different hashers are not comparable
+	storage, err = OpenDiskStorage(path)
+	assert.Nil(t, err)
+	defer storage.Close()
+	_, err = NewSearch(WithStorage(storage), Hashers(GenerateHashersSeeded(50, 99)), BandsNum(10))
+	assert.NotNil(t, err)
+}
+
+func Test_LoadSearchWithStorage_Disk(t *testing.T) {
+	path, cleanup := tempStoragePath(t)
+	defer cleanup()
+
+	search := newSnapshotSearch(t)
+	var buf bytes.Buffer
+	assert.Nil(t, search.Save(&buf))
+
+	storage, err := OpenDiskStorage(path)
+	assert.Nil(t, err)
+	loaded, err := LoadSearchWithStorage(bytes.NewReader(buf.Bytes()), storage)
+	assert.Nil(t, err)
+	assert.Equal(t, search.Len(), loaded.Len())
+	assert.Equal(t, find(t, search, aText), find(t, loaded, aText))
+	assert.Nil(t, loaded.Close())
+
+	// restored index persists and can't be restored into again
+	storage, err = OpenDiskStorage(path)
+	assert.Nil(t, err)
+	defer storage.Close()
+	_, err = LoadSearchWithStorage(bytes.NewReader(buf.Bytes()), storage)
+	assert.NotNil(t, err)
+
+	reopened, err := NewSearch(WithStorage(storage), BandsNum(10))
+	assert.Nil(t, err)
+	assert.Equal(t, find(t, search, aText), find(t, reopened, aText))
+}
+
+func Benchmark_DiskStorage_AddFrom(b *testing.B) {
+	path, cleanup := tempStoragePath(b)
+	defer cleanup()
+	storage, err := OpenDiskStorage(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer storage.Close()
+	idx, err := NewLSHIndexWithStorage(10, 2, storage)
+	if err != nil {
+		b.Fatal(err)
+	}
+	hashers := GenerateHashersSeeded(20, 1)
+	shingles := benchShingles(b.N, 20, 50000)
+
+	b.ResetTimer()
+	n := 0
+	_, err = idx.AddFrom(DocumentReaderFunc(func() (*Document, error) {
+		if n == len(shingles) {
+			return nil, io.EOF
+		}
+		n++
+		return &Document{ID: strconv.Itoa(n), Shingles: shingles[n-1]}, nil
+	}), hashers)
+	if err != nil {
+		b.Fatal(err)
+	}
+}
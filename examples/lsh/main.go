@@ -16,7 +16,11 @@ func main() {
 	signatureMatrix := lsh.Minhash([][]string{aShingles, bShingles}, 10)
 
 	// 3. perform LSH on the built signature matrix
-	bandBuckets := lsh.LSH(signatureMatrix, 3)
+	bandBuckets, err := lsh.LSH(signatureMatrix, 3)
+	if err != nil {
+		fmt.Printf("can't apply LSH: %v\n", err)
+		return
+	}
 
 	// 4. find candidate pairs in the return buckets
 	candidatePairs := bandBuckets.FindCandidatePairs()
@@ -2,51 +2,43 @@ package main
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/smeshkov/lsh"
 )
 
 func main() {
 
-	// 0. rember the order of documents
-	// * textA - 0
-	// * textB - 1
-	// * textC - 2
-	docsToIndex := map[string]int{
-		"textA": 0,
-		"textB": 1,
-		"textC": 2,
-	}
-	indexToDocs := []string{
-		"textA",
-		"textB",
-		"textC",
-	}
-
 	// 1. get shingles from given texts
 	aShingles := lsh.Shingle(textA)
 	bShingles := lsh.Shingle(textB)
 
-	// 2. build a sets matrix which will serve as an index
-	setsMatrix := lsh.ToSetsMatrix([][]string{aShingles, bShingles})
+	// 2. build a sets matrix which will serve as an index,
+	// documents are identified by the given IDs
+	setsMatrix, err := lsh.ToSetsMatrixWithIDs([]string{"textA", "textB"}, [][]string{aShingles, bShingles})
+	if err != nil {
+		fmt.Printf("can't build index: %v\n", err)
+		return
+	}
 
 	// 3. create an instance of search based on "setsMatrix" as an index
-	search := lsh.NewSearch(lsh.Index(setsMatrix))
-
-	// 4. find all candidates
-	allCandidates := search.Find(textC)
+	search, err := lsh.NewSearch(lsh.Index(setsMatrix))
+	if err != nil {
+		fmt.Printf("can't create search: %v\n", err)
+		return
+	}
 
-	// 5. "textA" is document 0, "textB" is document 1,
-	// "textC" is document 2 (hence the order of adding),
-	// therefore get all candidates for document 2 sorted by elections.
-	candidates := allCandidates.GetByKeySorted(docsToIndex["textC"])
+	// 4. find top 5 candidates sorted by estimated similarity
+	results, err := search.Find(textC, lsh.TopK(5))
+	if err != nil {
+		fmt.Printf("can't find: %v\n", err)
+		return
+	}
 
 	// 5. print results
-	fmt.Printf("found %d candidates\n", len(candidates))
-	if len(candidates) > 0 {
-		for k, v := range candidates {
-			fmt.Printf("[%d] %v\n", k, indexToDocs[v.Index])
-		}
+	fmt.Printf("found %d candidates\n", len(results))
+	for k, v := range results {
+		fmt.Printf("[%d] %s (score %.2f)\n", k, v.ID, v.Score)
 	}
 }
 
@@ -73,6 +65,6 @@ var (
 		"Watch a trailer below.",
 	}
 
-	// query for search
-	textC = "colors of Nintendo Switch Lite"
+	// query for search, near duplicate of "textB" without the last paragraphs
+	textC = strings.Join(textB[:5], " ")
 )
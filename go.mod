@@ -7,5 +7,6 @@ require (
 	github.com/stretchr/testify v1.3.0
 	github.com/zoomio/inout v0.6.0
 	github.com/zoomio/stopwords v0.2.0
-	golang.org/x/net v0.0.0-20190628185345-da137c7871d7 // indirect
+	go.etcd.io/bbolt v1.3.6
+	golang.org/x/text v0.3.8
 )
@@ -1,11 +1,25 @@
 package lsh
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
+	"math/bits"
 	"math/rand"
 )
 
+// mersennePrime61 is the Mersenne prime 2^61 - 1, used as a modulus of universal hash functions.
+const mersennePrime61 = 1<<61 - 1
+
+// Kinds of hash functions, used for serialisation of hashers.
+const (
+	kindModulus   = "modulus"
+	kindPatternX  = "patternX"
+	kindAnd       = "and"
+	kindBitShift  = "bitShift"
+	kindUniversal = "universal"
+)
+
 // HashFunc is a hash function.
 type HashFunc func(int, int) int
 
@@ -14,8 +28,23 @@ type Hasher struct {
 	// related hash function
 	hf HashFunc
 
+	// the same function applied to 64 bit values, used to hash shingles by content
+	hf64 func(uint64) uint64
+
 	// string representation of the function
 	t string
+
+	// definition of the function, allows to restore it after serialisation
+	def hasherDef
+}
+
+// hasherDef holds kind and parameters of the hash function.
+type hasherDef struct {
+	Kind        string `json:"kind"`
+	Multipier   int64  `json:"multiplier,omitempty"`
+	Coefficient int64  `json:"coefficient,omitempty"`
+	Ander       int64  `json:"ander,omitempty"`
+	Seed        *int64 `json:"seed,omitempty"`
 }
 
 // Hash retrurns hash function of the hasher.
@@ -23,10 +52,58 @@ func (h *Hasher) Hash() HashFunc {
 	return h.hf
 }
 
+// hash64 hashes the given 64 bit value, arithmetic wraps around modulo 2^64,
+// so results don't depend on the size of int of the target platform.
+func (h *Hasher) hash64(x uint64) uint64 {
+	return h.hf64(x)
+}
+
 func (h *Hasher) String() string {
 	return h.t
 }
 
+// MarshalJSON implements json.Marshaler,
+// it stores kind and parameters of the hash function.
+func (h *Hasher) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.def)
+}
+
+// UnmarshalJSON implements json.Unmarshaler,
+// it restores hash function from its kind and parameters.
+func (h *Hasher) UnmarshalJSON(data []byte) error {
+	var def hasherDef
+	if err := json.Unmarshal(data, &def); err != nil {
+		return err
+	}
+	restored, err := newHasher(def)
+	if err != nil {
+		return err
+	}
+	*h = *restored
+	return nil
+}
+
+// newHasher creates hash function from the given definition.
+func newHasher(def hasherDef) (*Hasher, error) {
+	var h *Hasher
+	switch def.Kind {
+	case kindModulus:
+		h = newModulus()
+	case kindPatternX:
+		h = NewPatternX(int(def.Multipier), int(def.Coefficient))
+	case kindAnd:
+		h = NewAnd(int(def.Multipier))
+	case kindBitShift:
+		h = NewBitShift(int(def.Multipier), int(def.Ander))
+	case kindUniversal:
+		h = NewUniversal(uint64(def.Multipier), uint64(def.Coefficient))
+	default:
+		return nil, fmt.Errorf("unknown kind of hash function: %q", def.Kind)
+	}
+	h.def.Seed = def.Seed
+	return h, nil
+}
+
 // SuggestHashNum suggests number of generated hashes based on the average number of shingles.
 func SuggestHashNum(avgNumOfShingles int) int {
 	if avgNumOfShingles <= 100 {
@@ -131,12 +208,36 @@ func GenerateHashers(amount int) []*Hasher {
 	return hashers
 }
 
+// GenerateHashersSeeded generates given amount of universal hash functions,
+// coefficients of the functions are derived from the given seed,
+// therefore the same seed always produces the same hashers across runs and machines.
+func GenerateHashersSeeded(amount int, seed int64) []*Hasher {
+	hashers := make([]*Hasher, amount)
+	rnd := &splitMix64{state: uint64(seed)}
+	for i := 0; i < amount; i++ {
+		// multipier must not be 0, otherwise all values end up in the same bucket
+		multipier := 1 + rnd.next()%(mersennePrime61-1)
+		coefficient := rnd.next() % mersennePrime61
+		hashers[i] = NewUniversal(multipier, coefficient)
+		hashers[i].def.Seed = &seed
+	}
+	return hashers
+}
+
 // Modulus is a simple modulus based hash function.
-var Modulus = &Hasher{
-	hf: func(x, numBuckets int) int {
-		return x % numBuckets
-	},
-	t: "x % numBuckets",
+var Modulus = newModulus()
+
+func newModulus() *Hasher {
+	return &Hasher{
+		hf: func(x, numBuckets int) int {
+			return x % numBuckets
+		},
+		hf64: func(x uint64) uint64 {
+			return x
+		},
+		t:   "x % numBuckets",
+		def: hasherDef{Kind: kindModulus},
+	}
 }
 
 // NewPatternX creates new hash function with provided multipier
@@ -146,7 +247,11 @@ func NewPatternX(multipier, coefficient int) *Hasher {
 		hf: func(x, numBuckets int) int {
 			return (multipier*x + coefficient) % numBuckets
 		},
-		t: fmt.Sprintf("(%d * x + %d) mod numBuckets", multipier, coefficient),
+		hf64: func(x uint64) uint64 {
+			return uint64(multipier)*x + uint64(coefficient)
+		},
+		t:   fmt.Sprintf("(%d * x + %d) mod numBuckets", multipier, coefficient),
+		def: hasherDef{Kind: kindPatternX, Multipier: int64(multipier), Coefficient: int64(coefficient)},
 	}
 }
 
@@ -156,7 +261,11 @@ func NewAnd(multipier int) *Hasher {
 		hf: func(x, numBuckets int) int {
 			return int(math.Abs(float64((multipier*x + x&math.MaxInt32) % numBuckets)))
 		},
-		t: fmt.Sprintf("(%d * x + x & maxInt) mod numBuckets", multipier),
+		hf64: func(x uint64) uint64 {
+			return uint64(multipier)*x + x&math.MaxInt32
+		},
+		t:   fmt.Sprintf("(%d * x + x & maxInt) mod numBuckets", multipier),
+		def: hasherDef{Kind: kindAnd, Multipier: int64(multipier)},
 	}
 }
 
@@ -167,7 +276,30 @@ func NewBitShift(multipier, ander int) *Hasher {
 		hf: func(x, numBuckets int) int {
 			return (((x * multipier) >> 28) & ander) % numBuckets
 		},
-		t: fmt.Sprintf("(((x * %d) >> 28) & %d) mod numBuckets", multipier, ander),
+		hf64: func(x uint64) uint64 {
+			return ((x * uint64(multipier)) >> 28) & uint64(ander)
+		},
+		t:   fmt.Sprintf("(((x * %d) >> 28) & %d) mod numBuckets", multipier, ander),
+		def: hasherDef{Kind: kindBitShift, Multipier: int64(multipier), Ander: int64(ander)},
+	}
+}
+
+// NewUniversal creates new universal hash function with provided multipier and coefficient,
+// i.e. ((multipier * x + coefficient) mod p) mod numBuckets, where "p" is the prime 2^61 - 1.
+func NewUniversal(multipier, coefficient uint64) *Hasher {
+	universal := func(x uint64) uint64 {
+		// 128 bit product guarantees there is no overflow before taking modulo
+		hi, lo := bits.Mul64(multipier%mersennePrime61, x)
+		_, h := bits.Div64(hi, lo, mersennePrime61)
+		return (h + coefficient) % mersennePrime61
+	}
+	return &Hasher{
+		hf: func(x, numBuckets int) int {
+			return int(universal(uint64(x)) % uint64(numBuckets))
+		},
+		hf64: universal,
+		t:    fmt.Sprintf("((%d * x + %d) mod %d) mod numBuckets", multipier, coefficient, uint64(mersennePrime61)),
+		def:  hasherDef{Kind: kindUniversal, Multipier: int64(multipier), Coefficient: int64(coefficient)},
 	}
 }
 
@@ -182,3 +314,17 @@ func randSign(k int) int {
 	}
 	return -k
 }
+
+// splitMix64 is a small deterministic pseudo random generator,
+// unlike "math/rand" its sequence is defined by the algorithm itself.
+type splitMix64 struct {
+	state uint64
+}
+
+func (s *splitMix64) next() uint64 {
+	s.state += 0x9e3779b97f4a7c15
+	z := s.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
@@ -1,6 +1,7 @@
 package lsh
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -48,3 +49,90 @@ func Test_PatternX(t *testing.T) {
 	assert.Equal(t, 0, hash2.Hash()(3, 5))
 	assert.Equal(t, 3, hash2.Hash()(4, 5))
 }
+
+func Test_GenerateHashersSeeded_reproducible(t *testing.T) {
+	a := GenerateHashersSeeded(50, 42)
+	b := GenerateHashersSeeded(50, 42)
+
+	assert.Len(t, a, 50)
+	assert.Len(t, b, 50)
+
+	for i := range a {
+		assert.Equal(t, a[i].String(), b[i].String())
+		for x := 0; x < 100; x++ {
+			assert.Equal(t, a[i].Hash()(x, 1000), b[i].Hash()(x, 1000))
+		}
+	}
+}
+
+func Test_GenerateHashersSeeded_differentSeeds(t *testing.T) {
+	a := GenerateHashersSeeded(10, 1)
+	b := GenerateHashersSeeded(10, 2)
+
+	for i := range a {
+		assert.NotEqual(t, a[i].String(), b[i].String())
+	}
+}
+
+func Test_GenerateHashersSeeded_stable(t *testing.T) {
+	// values are pinned in order to catch changes in generation of coefficients
+	hashers := GenerateHashersSeeded(1, 0)
+
+	assert.Equal(t, "((153307352162749886 * x + 1042757494553273847) mod 2305843009213693951) mod numBuckets",
+		hashers[0].String())
+}
+
+func Test_Universal(t *testing.T) {
+	hash := NewUniversal(3, 1)
+
+	assert.Equal(t, 1, hash.Hash()(0, 5))
+	assert.Equal(t, 4, hash.Hash()(1, 5))
+	assert.Equal(t, 2, hash.Hash()(2, 5))
+
+	// large values don't overflow
+	big := NewUniversal(mersennePrime61-1, 0)
+	assert.Equal(t, uint64(mersennePrime61-1), big.hash64(1))
+	assert.Equal(t, uint64(1), big.hash64(mersennePrime61-1))
+}
+
+func Test_Hasher_hash64(t *testing.T) {
+	hashers := append(GenerateHashers(20), GenerateHashersSeeded(5, 7)...)
+	for _, h := range hashers {
+		for x := 0; x < 100; x++ {
+			assert.Equal(t, toSignatureValue(h.Hash()(x, 1<<20), 1<<20), h.hash64(uint64(x))%(1<<20), h.String())
+		}
+	}
+}
+
+func Test_Hasher_JSON(t *testing.T) {
+	hashers := append(GenerateHashers(20), GenerateHashersSeeded(5, 7)...)
+
+	data, err := json.Marshal(hashers)
+	assert.Nil(t, err)
+
+	var restored []*Hasher
+	err = json.Unmarshal(data, &restored)
+	assert.Nil(t, err)
+
+	assert.Len(t, restored, len(hashers))
+	for i, h := range hashers {
+		assert.Equal(t, h.String(), restored[i].String())
+		assert.Equal(t, h.def, restored[i].def)
+		for x := 0; x < 100; x++ {
+			assert.Equal(t, h.Hash()(x, 97), restored[i].Hash()(x, 97))
+		}
+	}
+}
+
+func Test_Hasher_JSON_seed(t *testing.T) {
+	data, err := json.Marshal(GenerateHashersSeeded(1, 7)[0])
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"kind":"universal"`)
+	assert.Contains(t, string(data), `"seed":7`)
+}
+
+func Test_Hasher_JSON_unknownKind(t *testing.T) {
+	var h Hasher
+	err := json.Unmarshal([]byte(`{"kind":"unknown"}`), &h)
+	assert.NotNil(t, err)
+}
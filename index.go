@@ -0,0 +1,198 @@
+package lsh
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// addBatchSize is the number of documents added into storage in a single update
+// when documents are added in bulk, e.g. by LSHIndex#AddFrom.
+const addBatchSize = 1000
+
+// LSHIndex is an incremental LSH index of document signatures,
+// unlike BandBuckets it allows to add and remove documents at any time,
+// keeping buckets of each band up to date.
+//
+// LSHIndex is safe for concurrent use, queries run in parallel,
+// while updates are serialised, as old keys of a document must be read and replaced atomically.
+type LSHIndex struct {
+	mu sync.RWMutex
+
+	bands int
+	rows  int
+
+	// signatures and buckets of the indexed documents
+	storage Storage
+}
+
+// NewLSHIndex creates new empty in-memory index with the given number of bands and rows in each band,
+// signatures added to the index must have at least bands * rows values.
+func NewLSHIndex(bands, rows int) (*LSHIndex, error) {
+	return NewLSHIndexWithStorage(bands, rows, NewMemoryStorage())
+}
+
+// NewLSHIndexWithStorage creates new index with the given number of bands and rows in each band,
+// which keeps signatures and buckets in the given storage.
+func NewLSHIndexWithStorage(bands, rows int, storage Storage) (*LSHIndex, error) {
+	if bands < 1 {
+		return nil, fmt.Errorf("number of bands must be positive, got %d", bands)
+	}
+	if rows < 1 {
+		return nil, fmt.Errorf("number of rows in band must be positive, got %d", rows)
+	}
+	if err := storage.Init(bands, rows); err != nil {
+		return nil, err
+	}
+	return &LSHIndex{
+		bands:   bands,
+		rows:    rows,
+		storage: storage,
+	}, nil
+}
+
+// Len returns number of indexed documents.
+func (idx *LSHIndex) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.storage.Len()
+}
+
+// Signature returns signature of the indexed document.
+func (idx *LSHIndex) Signature(id string) (Signature, bool, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.storage.Get(id)
+}
+
+// Add adds document with the given ID and signature to the index,
+// previously added document with the same ID is replaced.
+func (idx *LSHIndex) Add(id string, sig Signature) error {
+	return idx.AddAll([]string{id}, []Signature{sig})
+}
+
+// AddAll adds documents with the given IDs and signatures to the index in a single update of the storage,
+// which is much faster than adding them one by one into on-disk storage,
+// previously added documents with the same IDs are replaced.
+func (idx *LSHIndex) AddAll(ids []string, sigs []Signature) error {
+	if len(ids) != len(sigs) {
+		return fmt.Errorf("got %d signatures for %d IDs", len(sigs), len(ids))
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	// keys of the documents, which are replaced within this update
+	pending := make(map[string][]string)
+	updates := make([]*StorageUpdate, len(ids))
+	for i, id := range ids {
+		if err := idx.checkSignature(sigs[i]); err != nil {
+			return err
+		}
+		oldKeys, ok := pending[id]
+		if !ok {
+			old, found, err := idx.storage.Get(id)
+			if err != nil {
+				return err
+			}
+			if found {
+				oldKeys = idx.bandKeys(old)
+			}
+		}
+		// copy, so changes to the given signature don't corrupt buckets
+		sig := append(Signature(nil), sigs[i]...)
+		keys := idx.bandKeys(sig)
+		pending[id] = keys
+		updates[i] = &StorageUpdate{ID: id, Signature: sig, Keys: keys, OldKeys: oldKeys}
+	}
+	return idx.storage.Apply(updates)
+}
+
+// Remove removes document with the given ID from the index,
+// returns false if there was no such document.
+func (idx *LSHIndex) Remove(id string) (bool, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	sig, ok, err := idx.storage.Get(id)
+	if err != nil || !ok {
+		return false, err
+	}
+	if err := idx.storage.Apply([]*StorageUpdate{{ID: id, OldKeys: idx.bandKeys(sig)}}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Query returns sorted IDs of the indexed documents,
+// which share at least one band with the given signature.
+func (idx *LSHIndex) Query(sig Signature) ([]string, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	elections, err := idx.query(sig)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(elections))
+	for id := range elections {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Close closes storage of the index.
+func (idx *LSHIndex) Close() error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return idx.storage.Close()
+}
+
+// query returns IDs of the indexed documents mapped to the number of bands
+// they share with the given signature.
+func (idx *LSHIndex) query(sig Signature) (map[string]int, error) {
+	if err := idx.checkSignature(sig); err != nil {
+		return nil, err
+	}
+	elections := make(map[string]int)
+	for b, key := range idx.bandKeys(sig) {
+		ids, err := idx.storage.Bucket(b, key)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			elections[id]++
+		}
+	}
+	return elections, nil
+}
+
+func (idx *LSHIndex) checkSignature(sig Signature) error {
+	if len(sig) < idx.bands*idx.rows {
+		return fmt.Errorf("signature has %d values, %d bands of %d rows need %d",
+			len(sig), idx.bands, idx.rows, idx.bands*idx.rows)
+	}
+	return nil
+}
+
+// bandKeys returns keys of the buckets of each band for the given signature,
+// documents without shingles are not similar to anything, hence have no buckets.
+func (idx *LSHIndex) bandKeys(sig Signature) []string {
+	if isEmptySignature(sig) {
+		return nil
+	}
+	keys := make([]string, idx.bands)
+	for b := range keys {
+		keys[b] = bandKey(sig[b*idx.rows : (b+1)*idx.rows])
+	}
+	return keys
+}
+
+func isEmptySignature(sig Signature) bool {
+	for _, v := range sig {
+		if v != EmptySet {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,140 @@
+package lsh
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LSHIndex_AddQuery(t *testing.T) {
+	hashers := GenerateHashersSeeded(20, 1)
+
+	idx, err := NewLSHIndex(10, 2)
+	assert.Nil(t, err)
+
+	assert.Nil(t, idx.Add("a", NewSignature(aShingles, hashers)))
+	assert.Nil(t, idx.Add("b", NewSignature(bShingles, hashers)))
+	assert.Nil(t, idx.Add("jim", NewSignature(Shingle([]string{"There was a boy whos name was Jim."}), hashers)))
+	assert.Equal(t, 3, idx.Len())
+
+	found, err := idx.Query(NewSignature(aShingles, hashers))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a"}, found)
+
+	found, err = idx.Query(NewSignature([]string{"nothing in common"}, hashers))
+	assert.Nil(t, err)
+	assert.Empty(t, found)
+}
+
+func Test_LSHIndex_Remove(t *testing.T) {
+	hashers := GenerateHashersSeeded(20, 1)
+
+	idx, err := NewLSHIndex(10, 2)
+	assert.Nil(t, err)
+
+	assert.Nil(t, idx.Add("a", NewSignature(aShingles, hashers)))
+	assert.Nil(t, idx.Add("a copy", NewSignature(aShingles, hashers)))
+
+	found, err := idx.Query(NewSignature(aShingles, hashers))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "a copy"}, found)
+
+	removed, err := idx.Remove("a")
+	assert.Nil(t, err)
+	assert.True(t, removed)
+	removed, err = idx.Remove("a")
+	assert.Nil(t, err)
+	assert.False(t, removed)
+	assert.Equal(t, 1, idx.Len())
+
+	found, err = idx.Query(NewSignature(aShingles, hashers))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a copy"}, found)
+
+	removed, err = idx.Remove("a copy")
+	assert.Nil(t, err)
+	assert.True(t, removed)
+	for _, band := range idx.storage.(*memoryStorage).buckets {
+		assert.Empty(t, band)
+	}
+}
+
+func Test_LSHIndex_Replace(t *testing.T) {
+	hashers := GenerateHashersSeeded(20, 1)
+
+	idx, err := NewLSHIndex(10, 2)
+	assert.Nil(t, err)
+
+	assert.Nil(t, idx.Add("doc", NewSignature(aShingles, hashers)))
+	assert.Nil(t, idx.Add("doc", NewSignature(cShingles, hashers)))
+	assert.Equal(t, 1, idx.Len())
+
+	found, err := idx.Query(NewSignature(aShingles, hashers))
+	assert.Nil(t, err)
+	assert.NotContains(t, found, "doc")
+}
+
+func Test_LSHIndex_EmptySignature(t *testing.T) {
+	hashers := GenerateHashersSeeded(4, 1)
+
+	idx, err := NewLSHIndex(2, 2)
+	assert.Nil(t, err)
+
+	assert.Nil(t, idx.Add("empty", NewSignature([]string{}, hashers)))
+
+	found, err := idx.Query(NewSignature([]string{}, hashers))
+	assert.Nil(t, err)
+	assert.Empty(t, found)
+}
+
+func Test_LSHIndex_invalid(t *testing.T) {
+	_, err := NewLSHIndex(0, 1)
+	assert.NotNil(t, err)
+	_, err = NewLSHIndex(1, 0)
+	assert.NotNil(t, err)
+
+	idx, err := NewLSHIndex(2, 2)
+	assert.Nil(t, err)
+
+	assert.NotNil(t, idx.Add("short", Signature{1, 2, 3}))
+	_, err = idx.Query(Signature{1, 2, 3})
+	assert.NotNil(t, err)
+}
+
+func Test_LSHIndex_concurrent(t *testing.T) {
+	hashers := GenerateHashersSeeded(20, 1)
+	aSig, bSig := NewSignature(aShingles, hashers), NewSignature(bShingles, hashers)
+
+	idx, err := NewLSHIndex(10, 2)
+	assert.Nil(t, err)
+	assert.Nil(t, idx.Add("a", aSig))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for k := 0; k < 20; k++ {
+				id := fmt.Sprintf("%d_%d", i, k)
+				assert.Nil(t, idx.Add(id, bSig))
+				if k%2 == 0 {
+					_, err := idx.Remove(id)
+					assert.Nil(t, err)
+				}
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for k := 0; k < 20; k++ {
+				found, err := idx.Query(aSig)
+				assert.Nil(t, err)
+				assert.Contains(t, found, "a")
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1+8*10, idx.Len())
+}
@@ -1,43 +1,58 @@
 package lsh
 
 import (
+	"encoding/binary"
 	"fmt"
-	"math"
 	"sort"
+	"strconv"
+	"strings"
 )
 
 // CandidatePair ...
 type CandidatePair struct {
-	A         int    // index of a candidate A
-	B         int    // index of a candidate B
+	A         string // ID of a candidate A
+	B         string // ID of a candidate B
 	Elections int    // how many times candidates ended up in the same bucket
 	signature string // unique signature that identifies candidates
 }
 
-func newCandidatePair(a, b int) *CandidatePair {
+func newCandidatePair(a, b string) *CandidatePair {
 	if a > b {
-		return &CandidatePair{
-			A:         b,
-			B:         a,
-			Elections: 1,
-			signature: fmt.Sprintf("%d_%d", b, a),
-		}
+		a, b = b, a
 	}
 	return &CandidatePair{
 		A:         a,
 		B:         b,
 		Elections: 1,
-		signature: fmt.Sprintf("%d_%d", a, b),
+		signature: pairSignature(a, b),
+	}
+}
+
+// pairSignature returns unique signature of the pair of ordered IDs in form of "A_B",
+// IDs which contain "_" or quotes are quoted as Go strings, e.g. "a_b"_c, so the form is unambiguous.
+func pairSignature(a, b string) string {
+	return quoteID(a) + "_" + quoteID(b)
+}
+
+// String returns the pair in form of "A_B", which is also its key in CandidatePairs#Index.
+func (cp *CandidatePair) String() string {
+	return pairSignature(cp.A, cp.B)
+}
+
+func quoteID(id string) string {
+	if strings.ContainsAny(id, `_"`) {
+		return strconv.Quote(id)
 	}
+	return id
 }
 
 // CandidatePairs ...
 type CandidatePairs struct {
-	Index map[string]*CandidatePair
+	Index map[string]*CandidatePair // candidate pairs by their String form
 }
 
 // Put ...
-func (c *CandidatePairs) Put(a, b int) {
+func (c *CandidatePairs) Put(a, b string) {
 	cp := newCandidatePair(a, b)
 	if _, ok := c.Index[cp.signature]; ok {
 		c.Index[cp.signature].Elections++
@@ -46,19 +61,29 @@ func (c *CandidatePairs) Put(a, b int) {
 	}
 }
 
-// Keys returns just keys of candidate pairs, useful for debugging or just printing to STDIN.
+// Get returns candidate pair of the given documents in any order.
+func (c *CandidatePairs) Get(a, b string) (*CandidatePair, bool) {
+	if a > b {
+		a, b = b, a
+	}
+	cp, ok := c.Index[pairSignature(a, b)]
+	return cp, ok
+}
+
+// Keys returns candidate pairs in form of "A_B" (see CandidatePair#String), useful for debugging or just printing to STDIN.
 func (c *CandidatePairs) Keys() []string {
 	var keys []string
 	for key := range c.Index {
 		keys = append(keys, key)
 	}
+	sort.Strings(keys)
 	return keys
 }
 
 // Candidate ...
 type Candidate struct {
-	Index     int // index of candidate
-	Elections int // how many times candidate ended up in the same bucket
+	ID        string // ID of candidate
+	Elections int    // how many times candidate ended up in the same bucket
 }
 
 // ------------------------------------------ Compare ------------------------------------------
@@ -101,28 +126,28 @@ func (s *itemSorter) Less(i, j int) bool {
 	return s.by(s.items[i], s.items[j])
 }
 
-// Candidates is an index of candidates, keyed by document signature (e.g. document number),
+// Candidates is an index of candidates, keyed by document ID,
 // with value representing list of documents which ended up in the same bucket.
 type Candidates struct {
-	Index map[int]map[int]*Candidate
+	Index map[string]map[string]*Candidate
 }
 
 // Put puts candidate "b" to the adjacent map of candidates of "a".
-func (c *Candidates) Put(a, b int) {
+func (c *Candidates) Put(a, b string) {
 	_, ok := c.Index[a]
 	if !ok {
-		c.Index[a] = make(map[int]*Candidate)
+		c.Index[a] = make(map[string]*Candidate)
 	}
 	_, ok = c.Index[a][b]
 	if !ok {
-		c.Index[a][b] = &Candidate{Index: b, Elections: 1}
+		c.Index[a][b] = &Candidate{ID: b, Elections: 1}
 	} else {
 		c.Index[a][b].Elections++
 	}
 }
 
 // GetByKey returns list of adjacent candidates for given candidate "key".
-func (c *Candidates) GetByKey(key int) []*Candidate {
+func (c *Candidates) GetByKey(key string) []*Candidate {
 	if cndMap, ok := c.Index[key]; ok {
 		res := make([]*Candidate, len(cndMap))
 		var i int
@@ -137,7 +162,7 @@ func (c *Candidates) GetByKey(key int) []*Candidate {
 
 // GetByKeySorted returns list of adjacent candidates for given candidate "key"
 // sorted by Elections in descending order.
-func (c *Candidates) GetByKeySorted(key int) []*Candidate {
+func (c *Candidates) GetByKeySorted(key string) []*Candidate {
 	candidates := c.GetByKey(key)
 	By(func(i1, i2 *Candidate) bool {
 		return i1.Elections > i2.Elections
@@ -147,64 +172,69 @@ func (c *Candidates) GetByKeySorted(key int) []*Candidate {
 
 type address struct {
 	bandNum int
-	setNum  int
+	id      string
 }
 
-type candidateBuckets [][]*address
+// candidateBuckets maps key of the band to the candidates, which have exactly the same band.
+type candidateBuckets map[string][]*address
 
 // BandBuckets stores candidates for comparisson in the same bucket,
 // bucket groups are separated by band.
 type BandBuckets struct {
-	bands []candidateBuckets
+	bands     []candidateBuckets
+	rows      int
+	numHashes int
 }
 
-func newBandBuckets(bands, buckets int) *BandBuckets {
+func newBandBuckets(bands, rows, numHashes int) *BandBuckets {
 	bb := &BandBuckets{
-		bands: make([]candidateBuckets, bands),
+		bands:     make([]candidateBuckets, bands),
+		rows:      rows,
+		numHashes: numHashes,
 	}
 	for index := 0; index < bands; index++ {
-		bb.bands[index] = make(candidateBuckets, buckets)
+		bb.bands[index] = make(candidateBuckets)
 	}
 	return bb
 }
 
-// hashToBucket hashes given vector into bucket and returns hash and bucket number.
-func (bb *BandBuckets) hashToBucket(vector []float64, bandNum, setNum int) (int, int) {
-	// hash vector to int
-	var h int
-	for _, v := range vector {
-		h = 31*h + (int(v) & math.MaxInt32)
-	}
-
-	// get buckets for given band number
-	buckets := bb.bands[bandNum]
-
-	// hash transformed int
-	// get backet addres for candidate
-	bucketNum := int(math.Abs(float64(h % len(buckets))))
-
-	// get backet for candidate
-	candidateBucket := buckets[bucketNum]
+// hashToBucket puts given vector into bucket and returns the key of the bucket.
+func (bb *BandBuckets) hashToBucket(vector []uint64, bandNum int, id string) string {
+	key := bandKey(vector)
 
 	// append candidate details to bucket
-	candidateBucket = append(candidateBucket, &address{
+	bb.bands[bandNum][key] = append(bb.bands[bandNum][key], &address{
 		bandNum: bandNum,
-		setNum:  setNum,
+		id:      id,
 	})
 
-	// put apdated list of candidates into bucket
-	buckets[bucketNum] = candidateBucket
+	return key
+}
 
-	// update bands with updated buckets
-	bb.bands[bandNum] = buckets
+// UnusedHashes returns indexes of the hash functions (rows of the signature matrix),
+// which don't belong to any band and therefore are not taken into account.
+func (bb *BandBuckets) UnusedHashes() []int {
+	unused := make([]int, 0)
+	for h := len(bb.bands) * bb.rows; h < bb.numHashes; h++ {
+		unused = append(unused, h)
+	}
+	return unused
+}
 
-	return h, bucketNum
+// bandKey returns the key of the bucket for the given band vector,
+// the key is made of the values themselves, so only equal vectors share the bucket.
+func bandKey(vector []uint64) string {
+	b := make([]byte, 8*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint64(b[8*i:], v)
+	}
+	return string(b)
 }
 
 // FindCandidates provides slice of candidate groups,
 // i.e. each entry in the slice is the list of candidates that ended up in the same bucket.
 func (bb *BandBuckets) FindCandidates() *Candidates {
-	candidates := &Candidates{Index: make(map[int]map[int]*Candidate)}
+	candidates := &Candidates{Index: make(map[string]map[string]*Candidate)}
 
 	// iterate through bands and pick up candidates for comparison from each of the bands
 	for _, band := range bb.bands {
@@ -215,8 +245,8 @@ func (bb *BandBuckets) FindCandidates() *Candidates {
 			}
 			for i := 0; i < len(bucket); i++ {
 				for j := i + 1; j < len(bucket); j++ {
-					candidates.Put(bucket[i].setNum, bucket[j].setNum)
-					candidates.Put(bucket[j].setNum, bucket[i].setNum)
+					candidates.Put(bucket[i].id, bucket[j].id)
+					candidates.Put(bucket[j].id, bucket[i].id)
 				}
 			}
 		}
@@ -239,8 +269,8 @@ func (bb *BandBuckets) FindCandidatePairs() *CandidatePairs {
 			}
 			for i := 0; i < len(bucket); i++ {
 				for j := i + 1; j < len(bucket); j++ {
-					candidates.Put(bucket[i].setNum, bucket[j].setNum)
-					candidates.Put(bucket[j].setNum, bucket[i].setNum)
+					candidates.Put(bucket[i].id, bucket[j].id)
+					candidates.Put(bucket[j].id, bucket[i].id)
 				}
 			}
 		}
@@ -249,22 +279,136 @@ func (bb *BandBuckets) FindCandidatePairs() *CandidatePairs {
 	return candidates
 }
 
+// LSH configuration options.
+var (
+	// RowsPerBand sets number of rows (hash functions) in each band,
+	// by default it is the number of hash functions divided by the number of bands.
+	RowsPerBand = func(rows int) LSHOption {
+		return func(o *lshOptions) {
+			o.rows = rows
+		}
+	}
+
+	// IDs sets IDs of the documents in the columns of signature matrix,
+	// by default documents are identified by their column numbers, i.e. "0", "1", etc.
+	IDs = func(ids []string) LSHOption {
+		return func(o *lshOptions) {
+			o.ids = ids
+		}
+	}
+)
+
+// LSHOption allows to customise LSH.
+type LSHOption func(*lshOptions)
+
+type lshOptions struct {
+	rows int
+	ids  []string
+}
+
 // LSH applies Locality Sesnitive Hashing (banded approach) onto the given signature matrix
 // in order to find candidate pairs for similiarity.
-func LSH(signatureMatrix [][]float64, bands int) *BandBuckets {
+//
+// Returns an error if the signature matrix can't be split into the given number of bands,
+// hash functions which don't fit into bands are reported by BandBuckets#UnusedHashes.
+func LSH(signatureMatrix SignatureMatrix, bands int, options ...LSHOption) (*BandBuckets, error) {
+	o := &lshOptions{}
+	for _, option := range options {
+		option(o)
+	}
+
+	numHashes := len(signatureMatrix)
+	if numHashes == 0 {
+		return nil, fmt.Errorf("signature matrix is empty")
+	}
+	if bands < 1 {
+		return nil, fmt.Errorf("number of bands must be positive, got %d", bands)
+	}
+	if bands > numHashes {
+		return nil, fmt.Errorf("number of bands %d is greater than number of hashes %d", bands, numHashes)
+	}
+	if o.rows == 0 {
+		o.rows = numHashes / bands
+	}
+	if o.rows < 1 {
+		return nil, fmt.Errorf("number of rows in band must be positive, got %d", o.rows)
+	}
+	if bands*o.rows > numHashes {
+		return nil, fmt.Errorf("%d bands of %d rows need %d hashes, got %d",
+			bands, o.rows, bands*o.rows, numHashes)
+	}
+	for h, row := range signatureMatrix {
+		if len(row) != len(signatureMatrix[0]) {
+			return nil, fmt.Errorf("row %d of signature matrix has %d sets, expected %d",
+				h, len(row), len(signatureMatrix[0]))
+		}
+	}
+
+	numSets := len(signatureMatrix[0])
+	if o.ids == nil {
+		o.ids = columnIDs(numSets)
+	}
+	if err := checkIDs(o.ids, numSets); err != nil {
+		return nil, err
+	}
+
+	return lsh(signatureMatrix, o.ids, bands, o.rows), nil
+}
+
+// columnIDs returns default IDs of the documents, which are their column numbers.
+func columnIDs(numSets int) []string {
+	ids := make([]string, numSets)
+	for i := range ids {
+		ids[i] = strconv.Itoa(i)
+	}
+	return ids
+}
+
+// checkIDs verifies that there is an unique ID for each of the sets (documents).
+func checkIDs(ids []string, numSets int) error {
+	if len(ids) != numSets {
+		return fmt.Errorf("got %d IDs for %d sets", len(ids), numSets)
+	}
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return fmt.Errorf("duplicate ID %q", id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// LSHWithThreshold applies Locality Sesnitive Hashing onto the given signature matrix,
+// number of bands and rows in each band are chosen by OptimalParams for the given Jaccard similarity threshold.
+func LSHWithThreshold(signatureMatrix SignatureMatrix, threshold float64, options ...LSHOption) (*BandBuckets, error) {
+	bands, rows, err := OptimalParams(threshold, len(signatureMatrix),
+		DefaultFalsePositiveWeight, DefaultFalseNegativeWeight)
+	if err != nil {
+		return nil, err
+	}
+	return LSH(signatureMatrix, bands, append(options, RowsPerBand(rows))...)
+}
+
+func lsh(signatureMatrix SignatureMatrix, ids []string, bands, numRows int) *BandBuckets {
 	numHashes := len(signatureMatrix)
 	numSets := len(signatureMatrix[0])
-	numBuckets := numHashes
-	numRows := numHashes / bands
 
 	// debug logging
-	// fmt.Printf("numBands %d, numHashes %d, numSets %d, numBuckets %d, numRows in band %d\n",
-	// bands, numHashes, numSets, numBuckets, numRows)
+	// fmt.Printf("numBands %d, numHashes %d, numSets %d, numRows in band %d\n",
+	// bands, numHashes, numSets, numRows)
 
-	bb := newBandBuckets(bands, numBuckets)
+	bb := newBandBuckets(bands, numRows, numHashes)
+
+	// documents without shingles are not similar to anything, hence are not put into buckets,
+	// the same as in LSHIndex
+	empty := make([]bool, numSets)
+	for s := 0; s < numSets; s++ {
+		empty[s] = isEmptySignature(signatureMatrix.Signature(s))
+	}
 
 	for b := 0; b < bands; b++ {
-		bandVectors := make([][]float64, numSets)
+		bandVectors := make([][]uint64, numSets)
 		bandOffset := b * numRows
 		bandEnd := (b + 1) * numRows
 
@@ -272,7 +416,7 @@ func LSH(signatureMatrix [][]float64, bands int) *BandBuckets {
 		// fmt.Printf("bandOffset %d, bandEnd %d\n",
 		// 	bandOffset, bandEnd)
 
-		for h := bandOffset; bandEnd <= numHashes && h < bandEnd; h++ {
+		for h := bandOffset; h < bandEnd; h++ {
 			for s := 0; s < numSets; s++ {
 				bandVectors[s] = append(bandVectors[s], signatureMatrix[h][s])
 			}
@@ -282,7 +426,9 @@ func LSH(signatureMatrix [][]float64, bands int) *BandBuckets {
 		// fmt.Printf("bandVectors:\n%v\n\n", bandVectors)
 
 		for i, vector := range bandVectors {
-			bb.hashToBucket(vector, b, i)
+			if !empty[i] {
+				bb.hashToBucket(vector, b, ids[i])
+			}
 		}
 	}
 
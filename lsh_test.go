@@ -26,18 +26,20 @@ func Test_LSH_equalCandidates(t *testing.T) {
 		2: {"A spokesperson for the Sudzo Corporation revealed today that studies have shown it is good for people to buy Sudzo products."},
 	}
 
-	buckets := LSH(Minhash(equalShingles, 5), 1)
+	buckets, err := LSH(Minhash(equalShingles, 5), 1)
+	assert.Nil(t, err)
+
 	candidates := buckets.FindCandidates()
 
-	candidatesOf0 := candidates.GetByKey(0)
-	candidatesOf2 := candidates.GetByKey(2)
+	candidatesOf0 := candidates.GetByKey("0")
+	candidatesOf2 := candidates.GetByKey("2")
 
 	assert.Equal(t, 1, len(candidatesOf0))
 	assert.Equal(t, 1, len(candidatesOf2))
 
-	assert.Equal(t, 2, candidatesOf0[0].Index)
+	assert.Equal(t, "2", candidatesOf0[0].ID)
 	assert.Equal(t, 1, candidatesOf0[0].Elections)
-	assert.Equal(t, 0, candidatesOf2[0].Index)
+	assert.Equal(t, "0", candidatesOf2[0].ID)
 	assert.Equal(t, 1, candidatesOf2[0].Elections)
 }
 
@@ -48,15 +50,17 @@ func Test_LSH_equalCandidatePairs(t *testing.T) {
 		2: {"A spokesperson for the Sudzo Corporation revealed today that studies have shown it is good for people to buy Sudzo products."},
 	}
 
-	buckets := LSH(Minhash(equalShingles, 5), 1)
+	buckets, err := LSH(Minhash(equalShingles, 5), 1)
+	assert.Nil(t, err)
+
 	candidatePairs := buckets.FindCandidatePairs()
 
 	assert.Equal(t, 1, len(candidatePairs.Index))
 
-	pair, ok := candidatePairs.Index["0_2"]
+	pair, ok := candidatePairs.Get("0", "2")
 	assert.True(t, ok)
-	assert.Equal(t, 0, pair.A)
-	assert.Equal(t, 2, pair.B)
+	assert.Equal(t, "0", pair.A)
+	assert.Equal(t, "2", pair.B)
 }
 
 func Test_LSH_similarCandidates(t *testing.T) {
@@ -66,18 +70,20 @@ func Test_LSH_similarCandidates(t *testing.T) {
 		2: bShingles,
 	}
 
-	buckets := LSH(Minhash(similarShingles, 5), 3)
+	buckets, err := LSH(Minhash(similarShingles, 5), 3)
+	assert.Nil(t, err)
+
 	candidates := buckets.FindCandidates()
 
-	candidatesOf0 := candidates.GetByKey(0)
-	candidatesOf2 := candidates.GetByKey(2)
+	candidatesOf0 := candidates.GetByKey("0")
+	candidatesOf2 := candidates.GetByKey("2")
 
 	assert.Equal(t, 1, len(candidatesOf0))
 	assert.Equal(t, 1, len(candidatesOf2))
 
-	assert.Equal(t, 2, candidatesOf0[0].Index)
+	assert.Equal(t, "2", candidatesOf0[0].ID)
 	assert.Equal(t, 1, candidatesOf0[0].Elections)
-	assert.Equal(t, 0, candidatesOf2[0].Index)
+	assert.Equal(t, "0", candidatesOf2[0].ID)
 	assert.Equal(t, 1, candidatesOf2[0].Elections)
 }
 
@@ -88,13 +94,146 @@ func Test_LSH_similarCandidatePairs(t *testing.T) {
 		2: bShingles,
 	}
 
-	buckets := LSH(Minhash(similarShingles, 5), 3)
+	buckets, err := LSH(Minhash(similarShingles, 5), 3)
+	assert.Nil(t, err)
+
+	candidatePairs := buckets.FindCandidatePairs()
+
+	assert.Equal(t, 1, len(candidatePairs.Index))
+
+	pair, ok := candidatePairs.Get("0", "2")
+	assert.True(t, ok)
+	assert.Equal(t, "0", pair.A)
+	assert.Equal(t, "2", pair.B)
+}
+
+func Test_LSH_exactBandKeys(t *testing.T) {
+	// band 0 - rows 0 and 1, band 1 - rows 2 and 3
+	signatureMatrix := SignatureMatrix{
+		{1, 2, 5},
+		{1, 2, 9},
+		{3, 3, 7},
+		{4, 4, 8},
+	}
+
+	buckets, err := LSH(signatureMatrix, 2)
+	assert.Nil(t, err)
+
 	candidatePairs := buckets.FindCandidatePairs()
 
+	// only 0 and 1 have exactly the same band
 	assert.Equal(t, 1, len(candidatePairs.Index))
 
-	pair, ok := candidatePairs.Index["0_2"]
+	pair, ok := candidatePairs.Get("0", "1")
 	assert.True(t, ok)
-	assert.Equal(t, 0, pair.A)
-	assert.Equal(t, 2, pair.B)
+	assert.Equal(t, "0", pair.A)
+	assert.Equal(t, "1", pair.B)
+}
+
+func Test_LSHWithThreshold(t *testing.T) {
+	shingles := [][]string{
+		0: aShingles,
+		1: {"There was a boy whos name was Jim. And all the friends were very good to him."},
+		2: aShingles,
+	}
+
+	buckets, err := LSHWithThreshold(MinhashWithHashers(shingles, GenerateHashersSeeded(50, 1), ByContent(true)), 0.8)
+	assert.Nil(t, err)
+
+	candidatePairs := buckets.FindCandidatePairs()
+	assert.Equal(t, 1, len(candidatePairs.Index))
+	_, ok := candidatePairs.Get("0", "2")
+	assert.True(t, ok)
+
+	_, err = LSHWithThreshold(MinhashWithHashers(shingles, GenerateHashersSeeded(50, 1)), 1.5)
+	assert.NotNil(t, err)
+}
+
+func Test_LSH_invalidParams(t *testing.T) {
+	signatureMatrix := SignatureMatrix{
+		{1, 2},
+		{1, 2},
+		{3, 3},
+	}
+
+	_, err := LSH(SignatureMatrix{}, 1)
+	assert.NotNil(t, err)
+
+	_, err = LSH(signatureMatrix, 0)
+	assert.NotNil(t, err)
+
+	_, err = LSH(signatureMatrix, 4)
+	assert.NotNil(t, err)
+
+	_, err = LSH(signatureMatrix, 2, RowsPerBand(2))
+	assert.NotNil(t, err)
+
+	_, err = LSH(SignatureMatrix{{1, 2}, {1}}, 1)
+	assert.NotNil(t, err)
+}
+
+func Test_LSH_unusedHashes(t *testing.T) {
+	signatureMatrix := SignatureMatrix{
+		{1, 2},
+		{1, 2},
+		{3, 3},
+		{4, 4},
+		{5, 5},
+	}
+
+	buckets, err := LSH(signatureMatrix, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{4}, buckets.UnusedHashes())
+
+	buckets, err = LSH(signatureMatrix, 3, RowsPerBand(1))
+	assert.Nil(t, err)
+	assert.Equal(t, []int{3, 4}, buckets.UnusedHashes())
+
+	// only the 3rd band has equal values
+	assert.Equal(t, 2, len(buckets.bands[0]))
+	assert.Equal(t, 2, len(buckets.bands[1]))
+	assert.Equal(t, 1, len(buckets.bands[2]))
+}
+
+func Test_LSH_IDs(t *testing.T) {
+	signatureMatrix := SignatureMatrix{
+		{1, 2, 1},
+		{1, 2, 1},
+	}
+
+	buckets, err := LSH(signatureMatrix, 1, IDs([]string{"a_b", "c", "b"}))
+	assert.Nil(t, err)
+
+	candidatePairs := buckets.FindCandidatePairs()
+	assert.Equal(t, []string{`"a_b"_b`}, candidatePairs.Keys())
+
+	pair, ok := candidatePairs.Get("b", "a_b")
+	assert.True(t, ok)
+	assert.Equal(t, "a_b", pair.A)
+	assert.Equal(t, "b", pair.B)
+	assert.Equal(t, pair, candidatePairs.Index[pair.String()])
+
+	candidates := buckets.FindCandidates().GetByKey("b")
+	assert.Len(t, candidates, 1)
+	assert.Equal(t, "a_b", candidates[0].ID)
+
+	assert.Equal(t, `a_"b_b"`, newCandidatePair("a", "b_b").String())
+	assert.Equal(t, `"http://x.com/a_b"_"say \"hi\""`, newCandidatePair("http://x.com/a_b", `say "hi"`).String())
+
+	_, err = LSH(signatureMatrix, 1, IDs([]string{"a", "b"}))
+	assert.NotNil(t, err)
+
+	_, err = LSH(signatureMatrix, 1, IDs([]string{"a", "b", "a"}))
+	assert.NotNil(t, err)
+}
+
+func Test_LSH_emptySets(t *testing.T) {
+	signatureMatrix := MinhashWithHashers([][]string{{}, {}, {"a"}, {"a"}}, GenerateHashersSeeded(4, 1), ByContent(true))
+
+	buckets, err := LSH(signatureMatrix, 2)
+	assert.Nil(t, err)
+
+	// documents without shingles are not candidates of each other
+	assert.Equal(t, []string{"2_3"}, buckets.FindCandidatePairs().Keys())
+	assert.Empty(t, buckets.FindCandidates().GetByKey("0"))
 }
@@ -3,44 +3,67 @@ package lsh
 import (
 	"fmt"
 	"math"
+	"runtime"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 )
 
 // SetsMatrix contains index of shingles to sets,
 // i.e. each key in the map is a string representation of shingle
-// and a value is a list of booleans corresponding to the documents,
-// true value in the list means that document contains the shingle.
+// and a value is a sorted list of columns of the documents which contain the shingle,
+// so memory grows with the total number of shingles in documents rather than shingles × documents.
 type SetsMatrix struct {
-	m       map[string][]bool
+	m       map[string][]int
 	setsNum int
+	ids     []string
 }
 
-// ToSetsMatrix returns unsorted matrix of shingles to sets.
+// ToSetsMatrixWithIDs returns unsorted matrix of shingles to sets,
+// where each set (document) is identified by the corresponding ID.
+func ToSetsMatrixWithIDs(ids []string, shingles [][]string) (*SetsMatrix, error) {
+	if err := checkIDs(ids, len(shingles)); err != nil {
+		return nil, err
+	}
+	setsMatrix := ToSetsMatrix(shingles)
+	setsMatrix.ids = append([]string(nil), ids...)
+	return setsMatrix, nil
+}
+
+// ToSetsMatrix returns unsorted matrix of shingles to sets,
+// sets (documents) are identified by their column numbers, i.e. "0", "1", etc.
 func ToSetsMatrix(shingles [][]string) *SetsMatrix {
-	m := make(map[string][]bool)
+	m := make(map[string][]int)
 
 	setsNum := len(shingles)
 
 	// iterate over provided sets of shingles
 	for c, set := range shingles {
 		for _, sh := range set {
-			// check if matrix representation of sets has row for shingle
-			// if it doesn't, then create it
-			_, ok := m[sh]
-			if !ok {
-				m[sh] = make([]bool, setsNum)
-			}
-			// set 1 (true) for row with key==sh and column==c,
-			// where "sh" is a shingle and "c" is a column/index of corresponding set/document
-			m[sh][c] = true
+			m[sh] = appendColumn(m[sh], c)
 		}
 	}
 
 	return &SetsMatrix{
 		m:       m,
 		setsNum: setsNum,
+		ids:     columnIDs(setsNum),
+	}
+}
+
+// appendColumn adds column "c" of corresponding set/document to the columns of a shingle,
+// columns are visited in order, so the last one is checked to skip repeated shingles.
+func appendColumn(columns []int, c int) []int {
+	if len(columns) > 0 && columns[len(columns)-1] == c {
+		return columns
 	}
+	return append(columns, c)
+}
+
+// IDs returns IDs of the sets (documents) in the order of columns.
+func (sm *SetsMatrix) IDs() []string {
+	return append([]string(nil), sm.ids...)
 }
 
 // ShinglesNum returns number of shingles.
@@ -48,28 +71,77 @@ func (sm *SetsMatrix) ShinglesNum() int {
 	return len(sm.m)
 }
 
-// Clone makes a copy of this SetsMatrix.
+// Clone makes a deep copy of this SetsMatrix.
 func (sm *SetsMatrix) Clone() *SetsMatrix {
-	m := make(map[string][]bool)
+	m := make(map[string][]int, len(sm.m))
 	for k, v := range sm.m {
-		m[k] = v
+		m[k] = append([]int(nil), v...)
 	}
 	return &SetsMatrix{
 		m:       m,
 		setsNum: sm.setsNum,
+		ids:     append([]string(nil), sm.ids...),
 	}
 }
 
+// HashedSetsMatrix is SetsMatrix of hashed shingles (see HashShingles),
+// i.e. each key in the map is a 64 bit hash of shingle
+// and a value is a sorted list of columns of the documents which contain the shingle.
+type HashedSetsMatrix struct {
+	m       map[uint64][]int
+	setsNum int
+	ids     []string
+}
+
+// ToHashedSetsMatrixWithIDs returns matrix of hashed shingles to sets,
+// where each set (document) is identified by the corresponding ID.
+func ToHashedSetsMatrixWithIDs(ids []string, shingles [][]uint64) (*HashedSetsMatrix, error) {
+	if err := checkIDs(ids, len(shingles)); err != nil {
+		return nil, err
+	}
+	setsMatrix := ToHashedSetsMatrix(shingles)
+	setsMatrix.ids = append([]string(nil), ids...)
+	return setsMatrix, nil
+}
+
+// ToHashedSetsMatrix returns matrix of hashed shingles to sets,
+// sets (documents) are identified by their column numbers, i.e. "0", "1", etc.
+func ToHashedSetsMatrix(shingles [][]uint64) *HashedSetsMatrix {
+	m := make(map[uint64][]int)
+	for c, set := range shingles {
+		for _, sh := range set {
+			m[sh] = appendColumn(m[sh], c)
+		}
+	}
+	return &HashedSetsMatrix{
+		m:       m,
+		setsNum: len(shingles),
+		ids:     columnIDs(len(shingles)),
+	}
+}
+
+// IDs returns IDs of the sets (documents) in the order of columns.
+func (hsm *HashedSetsMatrix) IDs() []string {
+	return append([]string(nil), hsm.ids...)
+}
+
+// ShinglesNum returns number of shingles.
+func (hsm *HashedSetsMatrix) ShinglesNum() int {
+	return len(hsm.m)
+}
+
 // SetsComputeMatrix optimised representation of SetsMatrix,
-// optimisation is in the way it stores values - two dimensional array of booleans
-// is smaller than map with strings as keys and arrays of booleans as values.
+// optimisation is in the way it stores values - for each set (document)
+// it keeps a sparse sorted list of rows of the shingles present in it,
+// which is smaller than map with strings as keys
+// and much smaller than a dense matrix of booleans, as documents contain only a fraction of all shingles.
 //
 // How optimisation is built?
 // Shingles are being sorted, so each shingle has certain ordered position,
 // therefore there is no more need in storing actual value of the shingle,
 // because it's position is just enough.
 type SetsComputeMatrix struct {
-	m       [][]bool
+	columns [][]int
 	rowsNum int
 	setsNum int
 }
@@ -87,41 +159,85 @@ func ToSetsComputeMatrix(setsMatrix *SetsMatrix) *SetsComputeMatrix {
 	}
 	sort.Strings(keys)
 
-	// Build optimised (only booleans) compute matrix
-	m := make([][]bool, rowsNum)
-	for i, key := range keys {
-		m[i] = make([]bool, setsMatrix.setsNum)
-		copy(m[i], setsMatrix.m[key])
+	// Build optimised (only row numbers) compute matrix,
+	// rows are visited in order, so each column stays sorted
+	columns := make([][]int, setsMatrix.setsNum)
+	for r, key := range keys {
+		for _, c := range setsMatrix.m[key] {
+			columns[c] = append(columns[c], r)
+		}
 	}
 	return &SetsComputeMatrix{
-		m:       m,
+		columns: columns,
 		rowsNum: rowsNum,
 		setsNum: setsMatrix.setsNum,
 	}
 }
 
+// String returns dense representation of the matrix, with a line of 0s and 1s per row.
 func (scm *SetsComputeMatrix) String() string {
 	var sb strings.Builder
-	for i, row := range scm.m {
-		for j, column := range row {
-			if j > 0 {
+	// position of the next row in every column
+	next := make([]int, scm.setsNum)
+	for r := 0; r < scm.rowsNum; r++ {
+		for c, rows := range scm.columns {
+			if c > 0 {
 				checkWriteStringError(sb.WriteString(","))
 			}
-			if column {
+			if next[c] < len(rows) && rows[next[c]] == r {
 				checkWriteStringError(sb.WriteString("1"))
+				next[c]++
 			} else {
 				checkWriteStringError(sb.WriteString("0"))
 			}
 		}
-		if i < len(scm.m)-1 {
+		if r < scm.rowsNum-1 {
 			checkWriteStringError(sb.WriteString("\n"))
 		}
 	}
 	return sb.String()
 }
 
+// EmptySet is a value of the signature matrix cell which is not set,
+// i.e. the corresponding set (document) doesn't have any shingles.
+const EmptySet uint64 = math.MaxUint64
+
 // SignatureMatrix - each row represents a hash function and each column represents a set.
-type SignatureMatrix [][]float64
+type SignatureMatrix [][]uint64
+
+// ToSignatureMatrix converts signature matrix of floats into SignatureMatrix,
+// NaN values are converted into EmptySet. Negative values produced by hash functions are wrapped
+// into the range of [0, numBuckets), so they never collide with EmptySet.
+// Legacy matrices took the minimum of the hashes before wrapping, while Minhash wraps every hash first,
+// so converted matrices are comparable with each other, but not with the ones computed by Minhash.
+func ToSignatureMatrix(m [][]float64, numBuckets int) (SignatureMatrix, error) {
+	if numBuckets < 1 {
+		return nil, fmt.Errorf("number of buckets must be positive, got %d", numBuckets)
+	}
+	sm := make(SignatureMatrix, len(m))
+	for i, row := range m {
+		sm[i] = make([]uint64, len(row))
+		for j, v := range row {
+			if math.IsNaN(v) {
+				sm[i][j] = EmptySet
+			} else {
+				sm[i][j] = toSignatureValue(int(v), numBuckets)
+			}
+		}
+	}
+	return sm, nil
+}
+
+func newSignatureMatrix(numHashes, setsNum int) SignatureMatrix {
+	sm := make(SignatureMatrix, numHashes)
+	for i := 0; i < numHashes; i++ {
+		sm[i] = make([]uint64, setsNum)
+		for k := 0; k < setsNum; k++ {
+			sm[i][k] = EmptySet
+		}
+	}
+	return sm
+}
 
 func (sm SignatureMatrix) String() string {
 	var sb strings.Builder
@@ -130,7 +246,11 @@ func (sm SignatureMatrix) String() string {
 			if j > 0 {
 				checkWriteStringError(sb.WriteString(","))
 			}
-			checkWriteStringError(sb.WriteString(fmt.Sprintf("%.0f", column)))
+			if column == EmptySet {
+				checkWriteStringError(sb.WriteString("-"))
+			} else {
+				checkWriteStringError(sb.WriteString(strconv.FormatUint(column, 10)))
+			}
 		}
 		if i < len(sm)-1 {
 			checkWriteStringError(sb.WriteString("\n"))
@@ -139,49 +259,206 @@ func (sm SignatureMatrix) String() string {
 	return sb.String()
 }
 
+// contentBuckets is the number of buckets used for hashing of shingles by content,
+// it keeps hashes below EmptySet.
+const contentBuckets uint64 = math.MaxInt64
+
+// Minhash configuration options.
+var (
+	// ByContent enables hashing of the shingles themselves instead of their row numbers
+	// in the sorted sets matrix, therefore signature of a document depends only on its own shingles
+	// and doesn't change when other documents are added.
+	ByContent = func(byContent bool) MinhashOption {
+		return func(o *minhashOptions) {
+			o.byContent = byContent
+		}
+	}
+	// Workers sets number of goroutines minhashing is split across,
+	// by documents or by hash functions when hashing by content,
+	// values less than 1 use all available CPUs, by default minhashing runs on a single goroutine.
+	Workers = func(workers int) MinhashOption {
+		return func(o *minhashOptions) {
+			if workers < 1 {
+				workers = runtime.NumCPU()
+			}
+			o.workers = workers
+		}
+	}
+)
+
+// MinhashOption allows to customise minhashing.
+type MinhashOption func(*minhashOptions)
+
+type minhashOptions struct {
+	byContent bool
+	workers   int
+}
+
+func newMinhashOptions(options []MinhashOption) *minhashOptions {
+	o := &minhashOptions{
+		workers: 1,
+	}
+	for _, option := range options {
+		option(o)
+	}
+	return o
+}
+
 // Minhash performs minhashing operations on the given shingles,
 // with the given number (`numHashes`) of generated hashes functions.
-func Minhash(shingles [][]string, numHashes int) SignatureMatrix {
-	return MinhashWithHashers(shingles, GenerateHashers(numHashes))
+func Minhash(shingles [][]string, numHashes int, options ...MinhashOption) SignatureMatrix {
+	return MinhashWithHashers(shingles, GenerateHashers(numHashes), options...)
 }
 
 // MinhashWithHashers performs minhashing operations on the given shingles,
 // with the given hashes functions.
-func MinhashWithHashers(shingles [][]string, hashers []*Hasher) SignatureMatrix {
-	return minhashSetsMatrix(ToSetsMatrix(shingles), hashers)
+func MinhashWithHashers(shingles [][]string, hashers []*Hasher, options ...MinhashOption) SignatureMatrix {
+	return minhashSetsMatrix(ToSetsMatrix(shingles), hashers, options...)
 }
 
-func minhashSetsMatrix(setsMatrix *SetsMatrix, hashers []*Hasher) SignatureMatrix {
-	setsComputeMatrix := ToSetsComputeMatrix(setsMatrix)
-	numHashes := len(hashers)
+// MinhashHashed performs minhashing operations on the given hashed shingles (see HashShingles),
+// with the given hashes functions, shingles are always hashed by content,
+// so the result is equal to minhashing of the corresponding string shingles with ByContent option.
+func MinhashHashed(shingles [][]uint64, hashers []*Hasher, options ...MinhashOption) SignatureMatrix {
+	return minhashHashedSetsMatrix(ToHashedSetsMatrix(shingles), hashers, newMinhashOptions(options).workers)
+}
 
-	// build a signature matrix, initialy by filing all the values with NaN.
-	minhash := make(SignatureMatrix, numHashes)
-	for i := 0; i < numHashes; i++ {
-		minhash[i] = make([]float64, setsComputeMatrix.setsNum)
-		for k := 0; k < setsComputeMatrix.setsNum; k++ {
-			minhash[i][k] = math.NaN()
+func minhashHashedSetsMatrix(setsMatrix *HashedSetsMatrix, hashers []*Hasher, workers int) SignatureMatrix {
+	// build a signature matrix, initialy by filing all the values with EmptySet.
+	minhash := newSignatureMatrix(len(hashers), setsMatrix.setsNum)
+
+	inParallel(len(hashers), workers, func(from, to int) {
+		for x, columns := range setsMatrix.m {
+			minhashShingle(minhash, x, columns, hashers[from:to], from)
 		}
+	})
+
+	return minhash
+}
+
+func minhashSetsMatrix(setsMatrix *SetsMatrix, hashers []*Hasher, options ...MinhashOption) SignatureMatrix {
+	o := newMinhashOptions(options)
+	if o.byContent {
+		return minhashSetsMatrixByContent(setsMatrix, hashers, o.workers)
 	}
 
-	// run through compute matrix and perform hashing,
-	// if set (document) has shingle represented in it.
-	for rNum, row := range setsComputeMatrix.m {
-		for cNum, column := range row {
-			if column {
+	setsComputeMatrix := ToSetsComputeMatrix(setsMatrix)
+	numHashes := len(hashers)
+
+	// build a signature matrix, initialy by filing all the values with EmptySet.
+	minhash := newSignatureMatrix(numHashes, setsComputeMatrix.setsNum)
+
+	// run through compute matrix and perform hashing
+	// of the rows of shingles represented in each set (document),
+	// every worker fills in its own range of columns.
+	inParallel(setsComputeMatrix.setsNum, o.workers, func(from, to int) {
+		for cNum := from; cNum < to; cNum++ {
+			for _, rNum := range setsComputeMatrix.columns[cNum] {
 				for i := 0; i < numHashes; i++ {
-					h := hashers[i].Hash()(rNum, setsComputeMatrix.rowsNum)
-					if math.IsNaN(minhash[i][cNum]) || minhash[i][cNum] > float64(h) {
-						minhash[i][cNum] = float64(h)
+					h := toSignatureValue(hashers[i].Hash()(rNum, setsComputeMatrix.rowsNum), setsComputeMatrix.rowsNum)
+					if minhash[i][cNum] > h {
+						minhash[i][cNum] = h
 					}
 				}
 			}
 		}
-	}
+	})
 
 	return minhash
 }
 
+// minhashSetsMatrixByContent performs minhashing of the 64 bit hashes of the shingles,
+// unlike row numbers these values don't depend on the rest of the shingles in the matrix.
+// Every worker fills in its own range of rows of the signature matrix, i.e. hash functions.
+func minhashSetsMatrixByContent(setsMatrix *SetsMatrix, hashers []*Hasher, workers int) SignatureMatrix {
+	numHashes := len(hashers)
+
+	// build a signature matrix, initialy by filing all the values with EmptySet.
+	minhash := newSignatureMatrix(numHashes, setsMatrix.setsNum)
+
+	inParallel(numHashes, workers, func(from, to int) {
+		for sh, columns := range setsMatrix.m {
+			minhashShingle(minhash, hashShingle(sh), columns, hashers[from:to], from)
+		}
+	})
+
+	return minhash
+}
+
+// minhashShingle updates rows of the signature matrix starting from `offset` with the hashes of the given hashed shingle
+// in the columns of sets (documents) which contain it.
+func minhashShingle(minhash SignatureMatrix, x uint64, columns []int, hashers []*Hasher, offset int) {
+	for _, cNum := range columns {
+		for i, hasher := range hashers {
+			if h := contentHash(hasher, x); minhash[offset+i][cNum] > h {
+				minhash[offset+i][cNum] = h
+			}
+		}
+	}
+}
+
+// contentHash returns hash of the given hashed shingle for signature.
+func contentHash(hasher *Hasher, x uint64) uint64 {
+	return hasher.hash64(x) % contentBuckets
+}
+
+// inParallel splits range [0, n) into contiguous chunks
+// and calls fn for each of them on its own goroutine, at most `workers` goroutines are used.
+// It returns when all the chunks are processed.
+func inParallel(n, workers int, fn func(from, to int)) {
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		fn(0, n)
+		return
+	}
+	var wg sync.WaitGroup
+	chunk := (n + workers - 1) / workers
+	for from := 0; from < n; from += chunk {
+		to := from + chunk
+		if to > n {
+			to = n
+		}
+		wg.Add(1)
+		go func(from, to int) {
+			defer wg.Done()
+			fn(from, to)
+		}(from, to)
+	}
+	wg.Wait()
+}
+
+// toSignatureValue brings negative results of hash functions
+// into the range of [0, numBuckets), so they never collide with EmptySet.
+func toSignatureValue(h, numBuckets int) uint64 {
+	if h < 0 {
+		h = h%numBuckets + numBuckets
+	}
+	return uint64(h)
+}
+
+// FNV-1a parameters, see hash/fnv.
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+// hashShingle returns 64 bit FNV-1a hash of the given shingle.
+func hashShingle(sh string) uint64 {
+	return fnvString(fnvOffset64, sh)
+}
+
+// fnvString continues 64 bit FNV-1a hash "h" with the bytes of the given string,
+// unlike hash/fnv it doesn't allocate, so shingles can be hashed piece by piece.
+func fnvString(h uint64, s string) uint64 {
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= fnvPrime64
+	}
+	return h
+}
+
 func checkWriteStringError(ignored int, err error) {
 	if err != nil {
 		panic(fmt.Sprintf("error in building a string from SetsComputeMatrix: %v", err))
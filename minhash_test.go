@@ -1,6 +1,8 @@
 package lsh
 
 import (
+	"math"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -28,12 +30,10 @@ func Test_ToSetsMatrix(t *testing.T) {
 	assert.Equal(t, setsMatrix.ShinglesNum(), len(intersection))
 
 	// Assert that 1st and 2nd sets have the same entry for shingle - "is good for"
-	assert.True(t, setsMatrix.m["is good for"][0])
-	assert.True(t, setsMatrix.m["is good for"][1])
+	assert.Equal(t, []int{0, 1}, setsMatrix.m["is good for"])
 
 	// Assert that 1st and 3rd sets have the same entry for shingle - "the Sudzo Corporation"
-	assert.True(t, setsMatrix.m["the Sudzo Corporation"][0])
-	assert.True(t, setsMatrix.m["the Sudzo Corporation"][2])
+	assert.Equal(t, []int{0, 2}, setsMatrix.m["the Sudzo Corporation"])
 }
 
 func Test_ToSetsMatrix2(t *testing.T) {
@@ -46,23 +46,19 @@ func Test_ToSetsMatrix2(t *testing.T) {
 	assert.Equal(t, setsMatrix.ShinglesNum(), 5)
 
 	// "a" is in set1 and set4
-	assert.True(t, setsMatrix.m["a"][0])
-	assert.True(t, setsMatrix.m["a"][3])
+	assert.Equal(t, []int{0, 3}, setsMatrix.m["a"])
 
 	// "b" is only in set3
-	assert.True(t, setsMatrix.m["b"][2])
+	assert.Equal(t, []int{2}, setsMatrix.m["b"])
 
 	// "c" is in set2 and set4
-	assert.True(t, setsMatrix.m["c"][1])
-	assert.True(t, setsMatrix.m["c"][3])
+	assert.Equal(t, []int{1, 3}, setsMatrix.m["c"])
 
 	// "d" is in set1, set3 and set4
-	assert.True(t, setsMatrix.m["d"][0])
-	assert.True(t, setsMatrix.m["d"][2])
-	assert.True(t, setsMatrix.m["d"][3])
+	assert.Equal(t, []int{0, 2, 3}, setsMatrix.m["d"])
 
 	// "e" is only in set3
-	assert.True(t, setsMatrix.m["e"][2])
+	assert.Equal(t, []int{2}, setsMatrix.m["e"])
 }
 
 func Test_ToSetsComputeMatrix(t *testing.T) {
@@ -76,12 +72,11 @@ func Test_ToSetsComputeMatrix(t *testing.T) {
 	//  4  |  0 |  0 |  1 |  0
 
 	expected := &SetsComputeMatrix{
-		m: [][]bool{
-			0: {true, false, false, true},
-			1: {false, false, true, false},
-			2: {false, true, false, true},
-			3: {true, false, true, true},
-			4: {false, false, true, false},
+		columns: [][]int{
+			0: {0, 3},
+			1: {2},
+			2: {1, 3, 4},
+			3: {0, 2, 3},
 		},
 		rowsNum: 5,
 		setsNum: 4,
@@ -89,16 +84,8 @@ func Test_ToSetsComputeMatrix(t *testing.T) {
 
 	actual := ToSetsComputeMatrix(ToSetsMatrix(simpleShingles))
 
-	assert.Equal(t, expected.rowsNum, actual.rowsNum)
-	assert.Equal(t, expected.setsNum, actual.setsNum)
-	assert.Equal(t, len(expected.m), len(actual.m))
-
-	for i, row := range expected.m {
-		assert.Equal(t, len(row), len(actual.m[i]))
-		for k, value := range row {
-			assert.Equal(t, value, actual.m[i][k])
-		}
-	}
+	assert.Equal(t, expected, actual)
+	assert.Equal(t, "1,0,0,1\n0,0,1,0\n0,1,0,1\n1,0,1,1\n0,0,1,0", actual.String())
 }
 
 func Test_MinHash_EnforcesOrder(t *testing.T) {
@@ -119,14 +106,191 @@ func Test_MinHash_EnforcesOrder(t *testing.T) {
 	// h2  |  0 |  2 |  0 |  0
 
 	// h1 hasing function assertions
-	assert.Equal(t, 1.0, minhash[0][0])
-	assert.Equal(t, 3.0, minhash[0][1])
-	assert.Equal(t, 0.0, minhash[0][2])
-	assert.Equal(t, 1.0, minhash[0][3])
+	assert.Equal(t, uint64(1), minhash[0][0])
+	assert.Equal(t, uint64(3), minhash[0][1])
+	assert.Equal(t, uint64(0), minhash[0][2])
+	assert.Equal(t, uint64(1), minhash[0][3])
 
 	// h2 hasing function assertions
-	assert.Equal(t, 0.0, minhash[1][0])
-	assert.Equal(t, 2.0, minhash[1][1])
-	assert.Equal(t, 0.0, minhash[1][2])
-	assert.Equal(t, 0.0, minhash[1][3])
+	assert.Equal(t, uint64(0), minhash[1][0])
+	assert.Equal(t, uint64(2), minhash[1][1])
+	assert.Equal(t, uint64(0), minhash[1][2])
+	assert.Equal(t, uint64(0), minhash[1][3])
+}
+
+func Test_MinHash_ByContent_IndependentOfCorpus(t *testing.T) {
+	hashers := GenerateHashersSeeded(10, 1)
+
+	before := MinhashWithHashers([][]string{aShingles, bShingles}, hashers, ByContent(true))
+	after := MinhashWithHashers([][]string{aShingles, bShingles, cShingles, {"brand new shingle"}}, hashers, ByContent(true))
+
+	for i := range hashers {
+		assert.Equal(t, before[i][0], after[i][0])
+		assert.Equal(t, before[i][1], after[i][1])
+	}
+}
+
+func Test_MinHash_ByContent_EqualSets(t *testing.T) {
+	minhash := MinhashWithHashers([][]string{{"a", "b"}, {"b", "a"}, {"c"}}, GenerateHashersSeeded(10, 1), ByContent(true))
+
+	for _, row := range minhash {
+		assert.Equal(t, row[0], row[1])
+		assert.NotEqual(t, row[0], row[2])
+	}
+}
+
+func Test_MinHash_EmptySet(t *testing.T) {
+	minhash := MinhashWithHashers([][]string{{"a"}, {}}, []*Hasher{NewPatternX(1, 1)})
+
+	assert.Equal(t, uint64(0), minhash[0][0])
+	assert.Equal(t, EmptySet, minhash[0][1])
+	assert.Equal(t, "0,-", minhash.String())
+}
+
+func Test_MinHash_NegativeHashes(t *testing.T) {
+	// (x - 1) mod 2 gives -1 for the 1st row, which is wrapped to 1
+	minhash := MinhashWithHashers([][]string{{"a"}, {"b"}}, []*Hasher{NewPatternX(1, -1)})
+
+	assert.Equal(t, uint64(1), minhash[0][0])
+	assert.Equal(t, uint64(0), minhash[0][1])
+}
+
+func Test_ToSignatureMatrix(t *testing.T) {
+	sm, err := ToSignatureMatrix([][]float64{
+		{1, math.NaN()},
+		{0, 3},
+	}, 5)
+	assert.Nil(t, err)
+
+	assert.Equal(t, SignatureMatrix{
+		{1, EmptySet},
+		{0, 3},
+	}, sm)
+
+	_, err = ToSignatureMatrix([][]float64{{1}}, 0)
+	assert.NotNil(t, err)
+}
+
+func Test_ToSignatureMatrix_Negative(t *testing.T) {
+	sm, err := ToSignatureMatrix([][]float64{{-1, 0}}, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, "1,0", sm.String())
+
+	sm, err = ToSignatureMatrix([][]float64{{-1, -2, 3}}, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, "4,3,3", sm.String())
+}
+
+func Test_ToSetsMatrixWithIDs(t *testing.T) {
+	setsMatrix, err := ToSetsMatrixWithIDs([]string{"a", "b", "c", "d"}, simpleShingles)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b", "c", "d"}, setsMatrix.IDs())
+
+	assert.Equal(t, []string{"0", "1", "2", "3"}, ToSetsMatrix(simpleShingles).IDs())
+
+	_, err = ToSetsMatrixWithIDs([]string{"a"}, simpleShingles)
+	assert.NotNil(t, err)
+}
+
+func Test_SetsMatrix_Clone(t *testing.T) {
+	setsMatrix := ToSetsMatrix(simpleShingles)
+	clone := setsMatrix.Clone()
+
+	clone.m["a"][1] = 1
+
+	assert.Equal(t, []int{0, 3}, setsMatrix.m["a"])
+	assert.Equal(t, setsMatrix.IDs(), clone.IDs())
+}
+
+func Test_MinHash_Workers(t *testing.T) {
+	shingles := benchShingles(101, 20, 500)
+	hashers := GenerateHashersSeeded(13, 1)
+
+	for _, byContent := range []bool{false, true} {
+		serial := MinhashWithHashers(shingles, hashers, ByContent(byContent))
+		for _, workers := range []int{0, 2, 7, 200} {
+			parallel := MinhashWithHashers(shingles, hashers, ByContent(byContent), Workers(workers))
+			assert.Equal(t, serial, parallel, "byContent %v, workers %d", byContent, workers)
+		}
+	}
+}
+
+func Test_MinHash_Hashed(t *testing.T) {
+	shingles := benchShingles(51, 20, 300)
+	hashed := make([][]uint64, len(shingles))
+	for i, set := range shingles {
+		hashed[i] = HashShingles(set)
+	}
+	hashers := GenerateHashersSeeded(13, 1)
+
+	expected := MinhashWithHashers(shingles, hashers, ByContent(true))
+	assert.Equal(t, expected, MinhashHashed(hashed, hashers))
+	assert.Equal(t, expected, MinhashHashed(hashed, hashers, Workers(3)))
+	assert.Equal(t, ToSetsMatrix(shingles).ShinglesNum(), ToHashedSetsMatrix(hashed).ShinglesNum())
+	assert.Equal(t, ToSetsMatrix(shingles).IDs(), ToHashedSetsMatrix(hashed).IDs())
+
+	setsMatrix, err := ToHashedSetsMatrixWithIDs([]string{"a", "b"}, [][]uint64{{1, 2, 1}, {2}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b"}, setsMatrix.IDs())
+	assert.Equal(t, map[uint64][]int{1: {0}, 2: {0, 1}}, setsMatrix.m)
+
+	_, err = ToHashedSetsMatrixWithIDs([]string{"a"}, hashed)
+	assert.NotNil(t, err)
+}
+
+// benchShingles generates sets of shingles for benchmarks,
+// each set has `size` shingles drawn from the vocabulary of `vocabulary` shingles.
+func benchShingles(sets, size, vocabulary int) [][]string {
+	gen := &splitMix64{state: 1}
+	shingles := make([][]string, sets)
+	for i := range shingles {
+		shingles[i] = make([]string, size)
+		for j := range shingles[i] {
+			shingles[i][j] = strconv.FormatUint(gen.next()%uint64(vocabulary), 10)
+		}
+	}
+	return shingles
+}
+
+func Benchmark_ToSetsComputeMatrix(b *testing.B) {
+	shingles := benchShingles(2000, 200, 50000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ToSetsComputeMatrix(ToSetsMatrix(shingles))
+	}
+}
+
+func Benchmark_Minhash(b *testing.B) {
+	shingles := benchShingles(2000, 200, 50000)
+	hashers := GenerateHashersSeeded(20, 1)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		MinhashWithHashers(shingles, hashers)
+	}
+}
+
+func Benchmark_Minhash_Workers(b *testing.B) {
+	shingles := benchShingles(2000, 200, 50000)
+	hashers := GenerateHashersSeeded(20, 1)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		MinhashWithHashers(shingles, hashers, Workers(0))
+	}
+}
+
+func Benchmark_MinhashHashed(b *testing.B) {
+	shingles := benchShingles(2000, 200, 50000)
+	hashed := make([][]uint64, len(shingles))
+	for i, set := range shingles {
+		hashed[i] = HashShingles(set)
+	}
+	hashers := GenerateHashersSeeded(20, 1)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		MinhashHashed(hashed, hashers)
+	}
 }
@@ -0,0 +1,130 @@
+package lsh
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalizer transforms text before shingling,
+// so texts which differ only in typography produce the same shingles.
+type Normalizer interface {
+	Normalize(text string) string
+}
+
+// NormalizerFunc allows to use an ordinary function as Normalizer.
+type NormalizerFunc func(text string) string
+
+// Normalize implements Normalizer.
+func (f NormalizerFunc) Normalize(text string) string {
+	return f(text)
+}
+
+// Normalization options, all the steps are enabled by default.
+var (
+	// NFKC enables or disables Unicode compatibility normalization (NFKC),
+	// e.g. it turns composed and decomposed forms of "é" and full-width "Ａ" into the same characters.
+	NFKC = func(enabled bool) NormalizerOption {
+		return func(o *normalizerOptions) {
+			o.nfkc = enabled
+		}
+	}
+
+	// FoldCase enables or disables Unicode case folding.
+	FoldCase = func(enabled bool) NormalizerOption {
+		return func(o *normalizerOptions) {
+			o.foldCase = enabled
+		}
+	}
+
+	// StripDiacritics enables or disables removal of diacritical marks, e.g. "café" becomes "cafe".
+	StripDiacritics = func(enabled bool) NormalizerOption {
+		return func(o *normalizerOptions) {
+			o.stripDiacritics = enabled
+		}
+	}
+
+	// UnifyPunctuation enables or disables replacement of curly quotes with straight ones
+	// and of all kinds of dashes with hyphen-minus.
+	UnifyPunctuation = func(enabled bool) NormalizerOption {
+		return func(o *normalizerOptions) {
+			o.unifyPunctuation = enabled
+		}
+	}
+
+	// CollapseWhitespace enables or disables replacement of whitespace runs with a single space,
+	// leading and trailing whitespace is removed.
+	CollapseWhitespace = func(enabled bool) NormalizerOption {
+		return func(o *normalizerOptions) {
+			o.collapseWhitespace = enabled
+		}
+	}
+)
+
+// NormalizerOption allows to customise normalization.
+type NormalizerOption func(*normalizerOptions)
+
+type normalizerOptions struct {
+	nfkc               bool
+	foldCase           bool
+	stripDiacritics    bool
+	unifyPunctuation   bool
+	collapseWhitespace bool
+}
+
+// punctuationReplacer replaces typographic quotes and dashes with their ASCII counterparts.
+var punctuationReplacer = strings.NewReplacer(
+	"‘", "'", "’", "'", "‚", "'", "‛", "'", "′", "'", "`", "'", "´", "'",
+	"“", `"`, "”", `"`, "„", `"`, "‟", `"`, "″", `"`, "«", `"`, "»", `"`,
+	"‐", "-", "‑", "-", "‒", "-", "–", "-", "—", "-", "―", "-", "−", "-",
+)
+
+// NewNormalizer returns Normalizer which applies NFKC normalization, case folding,
+// diacritics stripping, punctuation unification and whitespace collapsing in this order,
+// steps can be disabled via options.
+func NewNormalizer(options ...NormalizerOption) Normalizer {
+	o := &normalizerOptions{
+		nfkc:               true,
+		foldCase:           true,
+		stripDiacritics:    true,
+		unifyPunctuation:   true,
+		collapseWhitespace: true,
+	}
+	for _, option := range options {
+		option(o)
+	}
+
+	return NormalizerFunc(func(text string) string {
+		if o.nfkc {
+			text = norm.NFKC.String(text)
+		}
+		if o.foldCase {
+			// casers keep state, so a new one is used on every call
+			text = transformString(cases.Fold(), text)
+		}
+		if o.stripDiacritics {
+			text = transformString(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), text)
+		}
+		if o.unifyPunctuation {
+			text = punctuationReplacer.Replace(text)
+		}
+		if o.collapseWhitespace {
+			text = strings.Join(strings.Fields(text), " ")
+		}
+		return text
+	})
+}
+
+// transformString applies the given transformer to text,
+// text is returned as is if it can't be transformed.
+func transformString(t transform.Transformer, text string) string {
+	result, _, err := transform.String(t, text)
+	if err != nil {
+		return text
+	}
+	return result
+}
@@ -0,0 +1,36 @@
+package lsh
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Normalizer(t *testing.T) {
+	normalizer := NewNormalizer()
+
+	assert.Equal(t, "switch's", normalizer.Normalize("Switch’s"))
+	assert.Equal(t, normalizer.Normalize("Caf\u00e9"), normalizer.Normalize("Cafe\u0301"))
+	assert.Equal(t, "cafe", normalizer.Normalize("Café"))
+	assert.Equal(t, "strasse abc", normalizer.Normalize("STRASSE ＡＢＣ"))
+	assert.Equal(t, `"quoted" - text`, normalizer.Normalize(" “Quoted”\t—  text\n"))
+}
+
+func Test_Normalizer_Options(t *testing.T) {
+	normalizer := NewNormalizer(FoldCase(false), StripDiacritics(false), CollapseWhitespace(false))
+	assert.Equal(t, "Café 's ", normalizer.Normalize("Café ‘s "))
+
+	normalizer = NewNormalizer(NFKC(false), UnifyPunctuation(false))
+	assert.Equal(t, "ａｂｃ – x", normalizer.Normalize("ＡＢＣ  –  x"))
+}
+
+func Test_Shingle_Normalizer(t *testing.T) {
+	a := Shingle([]string{"The Switch’s price — for the “best” console."}, WithNormalizer(NewNormalizer()))
+	b := Shingle([]string{"the switch's price - for the \"best\" console"}, WithNormalizer(NewNormalizer()))
+	assert.NotEmpty(t, a)
+	assert.Equal(t, a, b)
+
+	assert.Equal(t,
+		KShingle([]string{"Café  “bar”"}, 4, WithNormalizer(NewNormalizer())),
+		KShingle([]string{"café \"BAR\""}, 4, WithNormalizer(NewNormalizer())))
+}
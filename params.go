@@ -0,0 +1,82 @@
+package lsh
+
+import (
+	"fmt"
+	"math"
+)
+
+// Default weights of false positives and false negatives used by LSHWithThreshold.
+const (
+	DefaultFalsePositiveWeight = 0.5
+	DefaultFalseNegativeWeight = 0.5
+)
+
+// integrationSteps is the number of steps used for numerical integration of probabilities.
+const integrationSteps = 100
+
+// OptimalParams finds number of bands and rows in each band for the given number of hash functions,
+// which minimise weighted sum of probabilities of false positives and false negatives
+// for the given Jaccard similarity threshold.
+//
+// Probability of becoming a candidate pair for sets with similarity "s" is 1 - (1 - s^r)^b,
+// false positives are the area under this curve below the threshold
+// and false negatives are the area above it starting from the threshold.
+func OptimalParams(threshold float64, numHashes int, fpWeight, fnWeight float64) (int, int, error) {
+	if threshold <= 0 || threshold >= 1 {
+		return 0, 0, fmt.Errorf("threshold must be in range (0, 1), got %v", threshold)
+	}
+	if numHashes < 1 {
+		return 0, 0, fmt.Errorf("number of hashes must be positive, got %d", numHashes)
+	}
+	if fpWeight < 0 || fnWeight < 0 {
+		return 0, 0, fmt.Errorf("weights must not be negative, got %v and %v", fpWeight, fnWeight)
+	}
+
+	minError := math.Inf(1)
+	var bands, rows int
+	for b := 1; b <= numHashes; b++ {
+		for r := 1; r <= numHashes/b; r++ {
+			fp := falsePositiveProbability(threshold, b, r)
+			fn := falseNegativeProbability(threshold, b, r)
+			err := fp*fpWeight + fn*fnWeight
+			if err < minError {
+				minError = err
+				bands = b
+				rows = r
+			}
+		}
+	}
+	return bands, rows, nil
+}
+
+// candidateProbability returns probability of the sets with similarity "s"
+// to become a candidate pair with "b" bands of "r" rows.
+func candidateProbability(s float64, b, r int) float64 {
+	return 1 - math.Pow(1-math.Pow(s, float64(r)), float64(b))
+}
+
+func falsePositiveProbability(threshold float64, b, r int) float64 {
+	return integrate(func(s float64) float64 {
+		return candidateProbability(s, b, r)
+	}, 0, threshold)
+}
+
+func falseNegativeProbability(threshold float64, b, r int) float64 {
+	return integrate(func(s float64) float64 {
+		return 1 - candidateProbability(s, b, r)
+	}, threshold, 1)
+}
+
+// integrate integrates given function over [a, b] using Simpson's rule.
+func integrate(f func(float64) float64, a, b float64) float64 {
+	step := (b - a) / integrationSteps
+	sum := f(a) + f(b)
+	for i := 1; i < integrationSteps; i++ {
+		if i%2 == 0 {
+			sum += 2 * f(a+float64(i)*step)
+		} else {
+			sum += 4 * f(a+float64(i)*step)
+		}
+	}
+	return sum * step / 3
+}
@@ -0,0 +1,43 @@
+package lsh
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_OptimalParams(t *testing.T) {
+	for _, threshold := range []float64{0.2, 0.5, 0.8} {
+		bands, rows, err := OptimalParams(threshold, 128, DefaultFalsePositiveWeight, DefaultFalseNegativeWeight)
+		assert.Nil(t, err)
+		assert.True(t, bands*rows <= 128)
+
+		// the steepest point of the S-curve is close to the threshold
+		assert.InDelta(t, 0.5, candidateProbability(threshold, bands, rows), 0.35)
+	}
+}
+
+func Test_OptimalParams_higherThresholdMoreRows(t *testing.T) {
+	_, lowRows, err := OptimalParams(0.3, 100, DefaultFalsePositiveWeight, DefaultFalseNegativeWeight)
+	assert.Nil(t, err)
+	_, highRows, err := OptimalParams(0.9, 100, DefaultFalsePositiveWeight, DefaultFalseNegativeWeight)
+	assert.Nil(t, err)
+
+	assert.True(t, highRows > lowRows)
+}
+
+func Test_OptimalParams_invalid(t *testing.T) {
+	_, _, err := OptimalParams(0, 100, 0.5, 0.5)
+	assert.NotNil(t, err)
+	_, _, err = OptimalParams(1, 100, 0.5, 0.5)
+	assert.NotNil(t, err)
+	_, _, err = OptimalParams(0.5, 0, 0.5, 0.5)
+	assert.NotNil(t, err)
+	_, _, err = OptimalParams(0.5, 100, -1, 0.5)
+	assert.NotNil(t, err)
+}
+
+func Test_integrate(t *testing.T) {
+	assert.InDelta(t, 0.5, integrate(func(x float64) float64 { return x }, 0, 1), 1e-9)
+	assert.InDelta(t, 1.0/3, integrate(func(x float64) float64 { return x * x }, 0, 1), 1e-9)
+}
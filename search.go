@@ -1,5 +1,13 @@
 package lsh
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
 // Search configuration options.
 var (
 	// Hashers sets hashers funcs.
@@ -9,10 +17,11 @@ var (
 		}
 	}
 
-	// HashersNum generates desired number of Hashers for Search.
+	// HashersNum generates desired number of Hashers for Search,
+	// hashers are seeded, so signatures are the same across runs.
 	HashersNum = func(hashersNum int) SearchOption {
 		return func(s *Search) {
-			s.hashers = GenerateHashers(hashersNum)
+			s.hashers = GenerateHashersSeeded(hashersNum, defaultSeed)
 		}
 	}
 
@@ -23,23 +32,65 @@ var (
 		}
 	}
 
-	// Index sets index for search.
+	// Threshold sets Jaccard similarity threshold,
+	// number of bands and rows in each band are chosen by OptimalParams instead of BandsNum.
+	Threshold = func(threshold float64) SearchOption {
+		return func(s *Search) {
+			s.threshold = threshold
+		}
+	}
+
+	// WithStorage sets storage of the search index, by default index is kept in memory,
+	// storage is closed by Search#Close.
+	WithStorage = func(storage Storage) SearchOption {
+		return func(s *Search) {
+			s.storage = storage
+		}
+	}
+
+	// Shingler sets function, which turns text of queries given to Search#Find
+	// and of documents given to Search#AddText and Search#AddFrom into shingles,
+	// it must be the same as the one used for shingles of the documents given to Search#Add.
+	// By default text is shingled via Shingle with default options.
+	Shingler = func(shingle func(text string) []string) SearchOption {
+		return func(s *Search) {
+			s.shingle = shingle
+		}
+	}
+
+	// Index sets initial documents for search,
+	// signatures of the documents are computed once on creation of Search.
 	Index = func(index *SetsMatrix) SearchOption {
 		return func(s *Search) {
-			s.index = index
+			s.setsMatrix = index
 		}
 	}
 )
 
-// Search ...
+// defaultSeed is the seed of the hashers generated for Search.
+const defaultSeed = 0
+
+// Search finds candidates for the query among indexed documents,
+// it keeps signatures and band buckets of the documents,
+// so only signature of the query is computed on search.
+//
+// Search is safe for concurrent use, queries run in parallel,
+// while updates of the index are serialised.
 type Search struct {
-	hashers  []*Hasher
-	bandsNum int
-	index    *SetsMatrix
+	mu sync.RWMutex
+
+	hashers    []*Hasher
+	bandsNum   int
+	rowsNum    int
+	threshold  float64
+	setsMatrix *SetsMatrix
+	shingle    func(text string) []string
+	storage    Storage
+	index      *LSHIndex
 }
 
 // NewSearch creates new instance of Search.
-func NewSearch(options ...SearchOption) Search {
+func NewSearch(options ...SearchOption) (*Search, error) {
 	s := &Search{}
 
 	// apply custom configuration
@@ -47,52 +98,258 @@ func NewSearch(options ...SearchOption) Search {
 		option(s)
 	}
 
-	// set defaults if needed
+	if s.storage == nil {
+		WithStorage(NewMemoryStorage())(s)
+	}
+
+	// set defaults if needed,
+	// hashers of the documents already in the storage take precedence over the default ones
+	if s.hashers == nil || len(s.hashers) == 0 {
+		hashers, err := storedHashers(s.storage)
+		if err != nil {
+			return nil, err
+		}
+		s.hashers = hashers
+	}
 	if s.hashers == nil || len(s.hashers) == 0 {
 		HashersNum(100)(s)
 	}
+	if s.threshold != 0 {
+		bands, rows, err := OptimalParams(s.threshold, len(s.hashers),
+			DefaultFalsePositiveWeight, DefaultFalseNegativeWeight)
+		if err != nil {
+			return nil, fmt.Errorf("can't choose bands for threshold: %v", err)
+		}
+		s.bandsNum = bands
+		s.rowsNum = rows
+	}
+	if s.bandsNum == 0 {
+		BandsNum(len(s.hashers) / 5)(s)
+	}
 	if s.bandsNum == 0 {
-		BandsNum(20)(s)
+		BandsNum(1)(s)
 	}
-	if s.index == nil {
-		Index(ToSetsMatrix([][]string{}))(s)
+	if s.bandsNum > len(s.hashers) {
+		return nil, fmt.Errorf("number of bands %d is greater than number of hashes %d",
+			s.bandsNum, len(s.hashers))
+	}
+	if s.rowsNum == 0 {
+		s.rowsNum = len(s.hashers) / s.bandsNum
+	}
+
+	index, err := NewLSHIndexWithStorage(s.bandsNum, s.rowsNum, s.storage)
+	if err != nil {
+		return nil, err
+	}
+	if err := storeHashers(s.storage, s.hashers); err != nil {
+		return nil, err
+	}
+	s.index = index
+
+	if s.setsMatrix != nil {
+		signatureMatrix := minhashSetsMatrix(s.setsMatrix, s.hashers, ByContent(true))
+		sigs := make([]Signature, len(s.setsMatrix.ids))
+		for i := range sigs {
+			sigs[i] = signatureMatrix.Signature(i)
+		}
+		if err := s.index.AddAll(s.setsMatrix.ids, sigs); err != nil {
+			return nil, err
+		}
+		// not needed anymore, all the documents are in the index
+		s.setsMatrix = nil
 	}
 
-	return *s
+	return s, nil
 }
 
-// Find finds candidates for given query string.
-func (s *Search) Find(query string) *Candidates {
-	shingles := Shingle([]string{query})
-	index := s.reIndex(shingles)
-	signatureMatrix := minhashSetsMatrix(index, s.hashers)
-	bandBuckets := LSH(signatureMatrix, s.bandsNum)
-	return bandBuckets.FindCandidates()
-	/* found := candidates.GetByKey(index.setsNum - 1)
-	result := make([]string, len(found))
-	for i, v := range found {
-		result[i] = s.documents[v.Index]
+// hashersProperty is the key of the storage property,
+// which keeps JSON of the hashers signatures of the stored documents are computed with.
+const hashersProperty = "hashers"
+
+// storedHashers returns hashers of the documents in the storage, nil is returned for a new storage.
+func storedHashers(storage Storage) ([]*Hasher, error) {
+	data, err := storage.Property(hashersProperty)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var hashers []*Hasher
+	if err := json.Unmarshal(data, &hashers); err != nil {
+		return nil, fmt.Errorf("can't unmarshal hashers of storage: %v", err)
+	}
+	return hashers, nil
+}
+
+// storeHashers saves the given hashers into a new storage
+// or verifies that documents in the storage are computed with the same hashers,
+// as signatures computed by different hash functions are not comparable.
+func storeHashers(storage Storage, hashers []*Hasher) error {
+	data, err := json.Marshal(hashers)
+	if err != nil {
+		return fmt.Errorf("can't marshal hashers: %v", err)
+	}
+	stored, err := storage.Property(hashersProperty)
+	if err != nil {
+		return err
 	}
-	return result */
+	if stored == nil {
+		return storage.SetProperty(hashersProperty, data)
+	}
+	if !bytes.Equal(stored, data) {
+		return fmt.Errorf("storage holds index built with different hashers")
+	}
+	return nil
+}
+
+// Add adds document with the given ID and shingles to the search index,
+// previously added document with the same ID is replaced.
+func (s *Search) Add(id string, shingles []string) error {
+	// compute signature before taking the lock, so queries are not blocked by hashing
+	sig := NewSignature(shingles, s.hashers)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.index.Add(id, sig)
 }
 
-// reIndex re-indexes current set of documents given the additional query.
-func (s *Search) reIndex(shingles []string) *SetsMatrix {
-	clone := s.index.Clone()
-	setIndex := clone.setsNum
-	clone.setsNum++
-	for _, sh := range shingles {
-		// check if matrix representation of sets has row for shingle
-		// if it doesn't, then create it
-		_, ok := clone.m[sh]
-		if !ok {
-			clone.m[sh] = make([]bool, clone.setsNum)
+// AddText adds document with the given ID and text to the search index,
+// text is turned into shingles by the Shingler of the search.
+func (s *Search) AddText(id, text string) error {
+	return s.Add(id, s.shingleText(text))
+}
+
+// AddFrom adds documents from the given reader into the search index,
+// documents are read and hashed one by one and added into storage in batches,
+// text of the documents without shingles is turned into shingles by the Shingler of the search,
+// returns number of added documents.
+func (s *Search) AddFrom(reader DocumentReader) (int, error) {
+	return addFrom(reader, s.hashers, s.shingleText, func(ids []string, sigs []Signature) error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.index.AddAll(ids, sigs)
+	})
+}
+
+// Remove removes document with the given ID from the search index,
+// returns false if there was no such document.
+func (s *Search) Remove(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.index.Remove(id)
+}
+
+// Len returns number of indexed documents.
+func (s *Search) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.index.Len()
+}
+
+// Close closes storage of the search index.
+func (s *Search) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.index.Close()
+}
+
+// Find configuration options.
+var (
+	// TopK limits number of the found results to the "k" best ones.
+	TopK = func(k int) FindOption {
+		return func(o *findOptions) {
+			o.topK = k
+		}
+	}
+
+	// MinScore filters out results with estimated Jaccard similarity lower than the given score.
+	MinScore = func(score float64) FindOption {
+		return func(o *findOptions) {
+			o.minScore = score
+		}
+	}
+)
+
+// FindOption allows to customise results of Search#Find.
+type FindOption func(*findOptions)
+
+type findOptions struct {
+	topK     int
+	minScore float64
+}
+
+// Result is a document found by Search.
+type Result struct {
+	ID        string  // ID of the document
+	Elections int     // how many bands of the document are the same as bands of the query
+	Score     float64 // Jaccard similarity of the document and the query estimated from their signatures
+}
+
+// Find finds candidates for given query string, which is turned into shingles by the Shingler of the search,
+// results are sorted by Score and then by Elections in descending order.
+func (s *Search) Find(query string, options ...FindOption) ([]*Result, error) {
+	return s.FindShingles(s.shingleText(query), options...)
+}
+
+// FindShingles finds candidates for the query given as shingles,
+// results are sorted by Score and then by Elections in descending order.
+func (s *Search) FindShingles(shingles []string, options ...FindOption) ([]*Result, error) {
+	o := &findOptions{}
+	for _, option := range options {
+		option(o)
+	}
+
+	results, err := s.find(NewSignature(shingles, s.hashers), o.minScore)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if results[i].Elections != results[j].Elections {
+			return results[i].Elections > results[j].Elections
+		}
+		return results[i].ID < results[j].ID
+	})
+
+	if o.topK > 0 && len(results) > o.topK {
+		results = results[:o.topK]
+	}
+	return results, nil
+}
+
+// shingleText turns the given text into shingles by the Shingler of the search.
+func (s *Search) shingleText(text string) []string {
+	if s.shingle == nil {
+		return defaultShingleText(text)
+	}
+	return s.shingle(text)
+}
+
+// find returns unsorted documents found for the given signature with score not lower than "minScore".
+func (s *Search) find(sig Signature, minScore float64) ([]*Result, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	elections, err := s.index.query(sig)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*Result, 0, len(elections))
+	for id, n := range elections {
+		docSig, _, err := s.index.Signature(id)
+		if err != nil {
+			return nil, err
+		}
+		// signatures are produced by the same hashers, hence have the same length
+		score, _ := sig.Jaccard(docSig)
+		if score < minScore {
+			continue
 		}
-		// set 1 (true) for row with key==sh and column==c,
-		// where "sh" is a shingle and "c" is a column/index of corresponding set/document
-		clone.m[sh][setIndex] = true
+		results = append(results, &Result{ID: id, Elections: n, Score: score})
 	}
-	return clone
+	return results, nil
 }
 
 // SearchOption allows to customise configuration.
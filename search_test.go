@@ -0,0 +1,145 @@
+package lsh
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const jimText = "There was a boy whos name was Jim. And all the friends were very good to him."
+
+func find(t *testing.T, search *Search, query string, options ...FindOption) []*Result {
+	results, err := search.Find(query, options...)
+	assert.Nil(t, err)
+	return results
+}
+
+func Test_Search_Find(t *testing.T) {
+	setsMatrix, err := ToSetsMatrixWithIDs([]string{"a", "jim"},
+		[][]string{aShingles, Shingle([]string{jimText})})
+	assert.Nil(t, err)
+
+	search, err := NewSearch(Index(setsMatrix))
+	assert.Nil(t, err)
+
+	results := find(t, search, aText)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "a", results[0].ID)
+	assert.Equal(t, 20, results[0].Elections)
+	assert.Equal(t, 1.0, results[0].Score)
+}
+
+func Test_Search_Find_ranked(t *testing.T) {
+	search, err := NewSearch(HashersNum(100), BandsNum(50))
+	assert.Nil(t, err)
+
+	assert.Nil(t, search.Add("a", aShingles))
+	assert.Nil(t, search.Add("a half", aShingles[:5]))
+	assert.Nil(t, search.Add("a most", aShingles[:8]))
+	assert.Nil(t, search.Add("jim", Shingle([]string{jimText})))
+
+	results := find(t, search, aText)
+	assert.Len(t, results, 3)
+	assert.Equal(t, "a", results[0].ID)
+	assert.Equal(t, "a most", results[1].ID)
+	assert.Equal(t, "a half", results[2].ID)
+	assert.InDelta(t, 0.8, results[1].Score, 0.15)
+	assert.InDelta(t, 0.5, results[2].Score, 0.15)
+
+	results = find(t, search, aText, TopK(1))
+	assert.Len(t, results, 1)
+	assert.Equal(t, "a", results[0].ID)
+
+	results = find(t, search, aText, MinScore(0.99))
+	assert.Len(t, results, 1)
+	assert.Equal(t, "a", results[0].ID)
+}
+
+func Test_Search_AddRemove(t *testing.T) {
+	search, err := NewSearch(HashersNum(50), BandsNum(10))
+	assert.Nil(t, err)
+
+	assert.Empty(t, find(t, search, aText))
+
+	assert.Nil(t, search.Add("a", aShingles))
+	assert.Nil(t, search.Add("jim", Shingle([]string{jimText})))
+
+	results := find(t, search, aText)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "a", results[0].ID)
+
+	removed, err := search.Remove("a")
+	assert.Nil(t, err)
+	assert.True(t, removed)
+	assert.Empty(t, find(t, search, aText))
+}
+
+func Test_Search_invalid(t *testing.T) {
+	_, err := NewSearch(HashersNum(10), BandsNum(20))
+	assert.NotNil(t, err)
+
+	_, err = NewSearch(Threshold(2))
+	assert.NotNil(t, err)
+}
+
+func Test_Search_concurrent(t *testing.T) {
+	search, err := NewSearch(HashersNum(50), BandsNum(10))
+	assert.Nil(t, err)
+	assert.Nil(t, search.Add("a", aShingles))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for k := 0; k < 20; k++ {
+				id := fmt.Sprintf("%d_%d", i, k)
+				assert.Nil(t, search.Add(id, bShingles))
+				if k%2 == 0 {
+					_, err := search.Remove(id)
+					assert.Nil(t, err)
+				}
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for k := 0; k < 20; k++ {
+				results := find(t, search, aText)
+				assert.NotEmpty(t, results)
+				assert.Equal(t, "a", results[0].ID)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1+8*10, search.Len())
+}
+
+func Test_Search_Shingler(t *testing.T) {
+	kShingle := func(text string) []string {
+		return KShingle([]string{text}, 5)
+	}
+	search, err := NewSearch(HashersNum(100), Shingler(kShingle))
+	assert.Nil(t, err)
+
+	assert.Nil(t, search.Add("a", kShingle(aText)))
+	assert.Nil(t, search.AddText("jim", jimText))
+	added, err := search.AddFrom(NewJSONLinesReader(strings.NewReader(`{"id": "duped", "text": "`+dupedText+`"}`), nil))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, added)
+
+	for id, text := range map[string]string{"a": aText, "jim": jimText, "duped": dupedText} {
+		results := find(t, search, text)
+		assert.NotEmpty(t, results)
+		assert.Equal(t, id, results[0].ID)
+		assert.Equal(t, 1.0, results[0].Score)
+	}
+
+	results, err := search.FindShingles(kShingle(aText), TopK(1))
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "a", results[0].ID)
+}
@@ -3,25 +3,151 @@ package lsh
 import (
 	"regexp"
 	"strings"
-
-	"github.com/zoomio/stopwords"
 )
 
 var (
 	punctuationMarks = regexp.MustCompile(`[.,:;?!]+`)
+	whitespaces      = regexp.MustCompile(`\s+`)
+)
+
+// WhitespaceMode defines how KShingle handles whitespace.
+type WhitespaceMode int
+
+// Whitespace modes of KShingle.
+const (
+	// WhitespaceKeep keeps whitespace as is.
+	WhitespaceKeep WhitespaceMode = iota
+	// WhitespaceCollapse replaces every run of whitespace with a single space.
+	WhitespaceCollapse
+	// WhitespaceDrop removes whitespace entirely, e.g. "touch down" matches "touchdown".
+	WhitespaceDrop
 )
 
+// defaultShingleWords is the default number of words in a shingle produced by Shingle.
+const defaultShingleWords = 3
+
+// Shingle configuration options.
+var (
+	// ShingleWords sets number of words in a shingle, values less than 1 are ignored.
+	ShingleWords = func(words int) ShingleOption {
+		return func(o *shingleOptions) {
+			if words > 0 {
+				o.words = words
+			}
+		}
+	}
+
+	// StopWordAnchors enables or disables anchoring of shingles on stop words,
+	// when it's disabled shingles are produced by a window sliding over all the words.
+	StopWordAnchors = func(anchors bool) ShingleOption {
+		return func(o *shingleOptions) {
+			o.stopWordAnchors = anchors
+		}
+	}
+
+	// Lowercase enables lowercasing of words in shingles.
+	Lowercase = func(lowercase bool) ShingleOption {
+		return func(o *shingleOptions) {
+			o.lowercase = lowercase
+		}
+	}
+
+	// WithStopWords sets stop words, which anchor shingles produced by Shingle,
+	// by default English stop words are used, built in stop words of other languages
+	// are returned by LanguageStopWords.
+	WithStopWords = func(stopWords StopWords) ShingleOption {
+		return func(o *shingleOptions) {
+			o.stopWords = stopWords
+		}
+	}
+
+	// DetectStopWordsLanguage enables detection of the language of the lines given to Shingle,
+	// stop words of the detected language are used instead of the configured ones.
+	DetectStopWordsLanguage = func(detect bool) ShingleOption {
+		return func(o *shingleOptions) {
+			o.detectLanguage = detect
+		}
+	}
+
+	// Whitespace sets handling of whitespace by KShingle, whitespace is kept by default.
+	Whitespace = func(mode WhitespaceMode) ShingleOption {
+		return func(o *shingleOptions) {
+			o.whitespace = mode
+		}
+	}
+
+	// LineSeparator sets separator, which KShingle inserts between lines,
+	// by default lines are joined without a separator.
+	LineSeparator = func(separator string) ShingleOption {
+		return func(o *shingleOptions) {
+			o.lineSeparator = separator
+		}
+	}
+
+	// ByteShingles enables shingles of k bytes in KShingle instead of k characters,
+	// byte shingles may split multi-byte characters.
+	ByteShingles = func(enabled bool) ShingleOption {
+		return func(o *shingleOptions) {
+			o.byteShingles = enabled
+		}
+	}
+
+	// WithNormalizer sets Normalizer, which is applied to every line before it's tokenized.
+	WithNormalizer = func(normalizer Normalizer) ShingleOption {
+		return func(o *shingleOptions) {
+			o.normalizer = normalizer
+		}
+	}
+
+	// WithTokenizer sets Tokenizer which splits lines into words,
+	// KShingle builds shingles from the tokens separated by a single space.
+	WithTokenizer = func(tokenizer Tokenizer) ShingleOption {
+		return func(o *shingleOptions) {
+			o.tokenizer = tokenizer
+		}
+	}
+)
+
+// ShingleOption allows to customise shingling by Shingle and KShingle,
+// options which don't apply to the shingler are ignored.
+type ShingleOption func(*shingleOptions)
+
+type shingleOptions struct {
+	words           int
+	stopWordAnchors bool
+	lowercase       bool
+	tokenizer       Tokenizer
+	normalizer      Normalizer
+	stopWords       StopWords
+	detectLanguage  bool
+	whitespace      WhitespaceMode
+	lineSeparator   string
+	byteShingles    bool
+}
+
+func newShingleOptions(options []ShingleOption) *shingleOptions {
+	o := &shingleOptions{
+		words:           defaultShingleWords,
+		stopWordAnchors: true,
+	}
+	for _, option := range options {
+		option(o)
+	}
+	return o
+}
+
+// shingler builds shingles of words, complete shingles are passed to the emit function.
 type shingler struct {
-	shingles   []string
 	candidates [][]string
-	seen       map[string]bool
+	words      int
+	emit       func(words []string)
 }
 
-func newShingler() *shingler {
+func newShingler(words int, emit func(words []string)) *shingler {
 	return &shingler{
-		shingles:   make([]string, 0),
 		candidates: make([][]string, 0),
-		seen:       make(map[string]bool),
+		words:      words,
+		emit:       emit,
 	}
 }
 
@@ -44,14 +170,8 @@ func (sh *shingler) appendWord(word string) {
 
 		sh.candidates[i] = append(sh.candidates[i], word)
 
-		if len(sh.candidates[i]) == 3 {
-			// append to result shingles
-			candidate := strings.Join(sh.candidates[i], " ")
-			// append result to candidates only if not seen before
-			if s, ok := sh.seen[candidate]; !ok && !s {
-				sh.shingles = append(sh.shingles, candidate)
-				sh.seen[candidate] = true
-			}
+		if len(sh.candidates[i]) == sh.words {
+			sh.emit(sh.candidates[i])
 			// delete from candidates
 			if i == candidatesLen-1 {
 				sh.candidates = append([][]string(nil), sh.candidates[:i]...)
@@ -67,82 +187,182 @@ func (sh *shingler) appendWord(word string) {
 }
 
 // Shingle produces shingles of a stop word followed by
-// the next two words from the given lines of strings.
-func Shingle(lines []string) []string {
-	sh := newShingler()
+// the next two words from the given lines of strings, English stop words are used by default,
+// number of words, stop words and anchoring on them, lowercasing, normalization and tokenization
+// can be changed via options.
+func Shingle(lines []string, options ...ShingleOption) []string {
+	shingles := make([]string, 0)
+	seen := make(map[string]bool)
+	shingleWords(lines, newShingleOptions(options), func(words []string) {
+		// append shingle only if it is not seen before
+		if sh := strings.Join(words, " "); !seen[sh] {
+			shingles = append(shingles, sh)
+			seen[sh] = true
+		}
+	})
+	return shingles
+}
+
+// Shingle64 produces shingles like Shingle, but returns their 64 bit hashes (see HashShingles),
+// shingles are hashed directly from words without building strings.
+func Shingle64(lines []string, options ...ShingleOption) []uint64 {
+	shingles := make([]uint64, 0)
+	seen := make(map[uint64]bool)
+	shingleWords(lines, newShingleOptions(options), func(words []string) {
+		h := uint64(fnvOffset64)
+		for i, w := range words {
+			if i > 0 {
+				h = fnvString(h, " ")
+			}
+			h = fnvString(h, w)
+		}
+		// append shingle only if it is not seen before
+		if !seen[h] {
+			shingles = append(shingles, h)
+			seen[h] = true
+		}
+	})
+	return shingles
+}
 
-	for _, line := range lines {
+// shingleWords passes words of every shingle of the given lines to the emit function in order,
+// the same shingle may be passed several times.
+func shingleWords(lines []string, o *shingleOptions, emit func(words []string)) {
+	sh := newShingler(o.words, emit)
+	tokenizer := o.tokenizer
+	if tokenizer == nil {
+		tokenizer = NewWhitespaceTokenizer()
+	}
 
-		words := strings.Fields(line)
-		for _, word := range words {
-			w := removePunctuationMarks(word)
-			if stopwords.IsStopWord(strings.ToLower(w)) {
+	words := make([][]string, len(lines))
+	for i, line := range lines {
+		if o.normalizer != nil {
+			line = o.normalizer.Normalize(line)
+		}
+		words[i] = tokenizer.Tokenize(line)
+	}
+
+	isStopWord := isEnglishStopWord
+	if o.stopWords != nil {
+		isStopWord = o.stopWords.IsStopWord
+	}
+	if o.detectLanguage {
+		if sw, err := languageStopWordsOf(DetectLanguage(flatten(words))); err == nil {
+			isStopWord = sw.IsStopWord
+		}
+	}
+
+	for _, lineWords := range words {
+		for _, w := range lineWords {
+			lower := strings.ToLower(w)
+			if !o.stopWordAnchors || isStopWord(lower) {
 				sh.appendCandidate()
 			}
+			if o.lowercase {
+				w = lower
+			}
 			sh.appendWord(w)
 		}
 	}
+}
 
-	return sh.shingles
+func flatten(words [][]string) []string {
+	flat := make([]string, 0)
+	for _, lineWords := range words {
+		flat = append(flat, lineWords...)
+	}
+	return flat
 }
 
-// KShingle produces shingles of given size k.
-func KShingle(lines []string, k int) []string {
+// KShingle produces shingles of given size k, i.e. all distinct substrings of k characters,
+// lines are joined without a separator and punctuation marks are skipped by default,
+// whitespace handling, line separator, byte shingles, lowercasing, normalization and tokenization
+// can be changed via options.
+func KShingle(lines []string, k int, options ...ShingleOption) []string {
 	shingles := make([]string, 0)
-	candidates := make([]*strings.Builder, 0)
-
 	seen := make(map[string]bool)
-
-	for _, line := range lines {
-		for _, char := range line {
-			if isPunctuationMark(char) {
-				continue
-			}
-			candidates = append(candidates, &strings.Builder{})
-			candidatesLen := len(candidates)
-
-			i := 0
-			for {
-				if i == candidatesLen {
-					break
-				}
-
-				sb := candidates[i]
-				_, err := sb.WriteRune(char)
-				if err != nil {
-					// unexpected -> panic
-					panic(err)
-				}
-
-				if sb.Len() == k {
-					shingles, candidates = updateShingles(i, sb.String(), seen, candidates, shingles)
-
-					candidatesLen--
-				} else {
-					i++
-				}
-			}
+	kShingleWindows(lines, k, newShingleOptions(options), func(window string) {
+		// append shingle only if it is not seen before
+		if !seen[window] {
+			shingles = append(shingles, window)
+			seen[window] = true
 		}
-	}
+	})
+	return shingles
+}
 
+// KShingle64 produces shingles of given size k like KShingle, but returns their 64 bit hashes (see HashShingles),
+// shingles are hashed directly from the text without building strings.
+func KShingle64(lines []string, k int, options ...ShingleOption) []uint64 {
+	shingles := make([]uint64, 0)
+	seen := make(map[uint64]bool)
+	kShingleWindows(lines, k, newShingleOptions(options), func(window string) {
+		// append shingle only if it is not seen before
+		if h := fnvString(fnvOffset64, window); !seen[h] {
+			shingles = append(shingles, h)
+			seen[h] = true
+		}
+	})
 	return shingles
 }
 
-func updateShingles(i int, candidate string, seenCandidates map[string]bool, candidates []*strings.Builder,
-	shingles []string) ([]string, []*strings.Builder) {
-	// append result to candidates only if it is not seen before
-	if s, ok := seenCandidates[candidate]; !ok && !s {
-		shingles = append(shingles, candidate)
-		seenCandidates[candidate] = true
+// kShingleWindows passes every substring of k characters (or bytes) of the prepared text
+// to the emit function in order, the same substring may be passed several times.
+func kShingleWindows(lines []string, k int, o *shingleOptions, emit func(window string)) {
+	if k < 1 {
+		return
 	}
-	// delete from candidates
-	if i == len(candidates)-1 {
-		candidates = append([]*strings.Builder(nil), candidates[:i]...)
-	} else {
-		candidates = append(candidates[:i], candidates[i+1:]...)
+
+	prepared := make([]string, len(lines))
+	for i, line := range lines {
+		if o.normalizer != nil {
+			line = o.normalizer.Normalize(line)
+		}
+		if o.tokenizer != nil {
+			line = strings.Join(o.tokenizer.Tokenize(line), " ")
+		} else {
+			line = removePunctuationMarks(line)
+		}
+		prepared[i] = line
 	}
 
-	return shingles, candidates
+	text := strings.Join(prepared, o.lineSeparator)
+	switch o.whitespace {
+	case WhitespaceCollapse:
+		text = whitespaces.ReplaceAllString(text, " ")
+	case WhitespaceDrop:
+		text = whitespaces.ReplaceAllString(text, "")
+	}
+	if o.lowercase {
+		text = strings.ToLower(text)
+	}
+
+	if o.byteShingles {
+		for i := 0; i+k <= len(text); i++ {
+			emit(text[i : i+k])
+		}
+		return
+	}
+
+	// byte offsets of the characters and of the end of text
+	starts := make([]int, 0, len(text)+1)
+	for i := range text {
+		starts = append(starts, i)
+	}
+	starts = append(starts, len(text))
+	for i := 0; i+k < len(starts); i++ {
+		emit(text[starts[i]:starts[i+k]])
+	}
+}
+
+// HashShingles returns 64 bit FNV-1a hashes of the given shingles,
+// which take less memory than strings and are used by minhashing of shingles by content.
+func HashShingles(shingles []string) []uint64 {
+	hashes := make([]uint64, len(shingles))
+	for i, sh := range shingles {
+		hashes[i] = hashShingle(sh)
+	}
+	return hashes
 }
 
 func isPunctuationMark(char rune) bool {
@@ -56,6 +56,25 @@ func Test_Shingle_DeDuplicates(t *testing.T) {
 	assert.Equal(t, "the Sudzo Corporation", shingles[2])
 }
 
+func Test_Shingle_Words(t *testing.T) {
+	shingles := Shingle([]string{aText}, ShingleWords(2))
+
+	assert.Equal(t, []string{
+		"A spokesperson", "for the", "the Sudzo", "that studies", "have shown",
+		"shown it", "it is", "is good", "for people", "to buy",
+	}, shingles)
+}
+
+func Test_Shingle_SlidingWindow(t *testing.T) {
+	shingles := Shingle([]string{"Fast Red Car for sale."}, StopWordAnchors(false), Lowercase(true))
+	assert.Equal(t, []string{"fast red car", "red car for", "car for sale"}, shingles)
+
+	shingles = Shingle([]string{aText}, ShingleWords(5), StopWordAnchors(false))
+	assert.Len(t, shingles, 17)
+	assert.Equal(t, "A spokesperson for the Sudzo", shingles[0])
+	assert.Equal(t, "people to buy Sudzo products", shingles[len(shingles)-1])
+}
+
 func Test_KShingle(t *testing.T) {
 	shingles := KShingle([]string{aText}, 9)
 
@@ -69,6 +88,49 @@ func Test_KShingle(t *testing.T) {
 	assert.Equal(t, " products", shingles[len(shingles)-1])
 }
 
+func Test_KShingle_Book(t *testing.T) {
+	// Example 3.3 of "Mining of Massive Datasets"
+	assert.Equal(t, []string{"ab", "bc", "cd", "da", "bd"}, KShingle([]string{"abcdabd"}, 2))
+
+	// "touch down" and "touchdown" share shingles once whitespace is dropped
+	a := KShingle([]string{"The plane was ready for touch down"}, 9, Whitespace(WhitespaceDrop))
+	b := KShingle([]string{"The quarterback scored a touchdown"}, 9, Whitespace(WhitespaceDrop))
+	assert.Contains(t, a, "touchdown")
+	assert.Contains(t, b, "touchdown")
+	assert.NotContains(t, KShingle([]string{"The plane was ready for touch down"}, 9), "touchdown")
+}
+
+func Test_KShingle_Whitespace(t *testing.T) {
+	assert.Equal(t, []string{"a  ", "  b", " b "}, KShingle([]string{"a  b "}, 3))
+	assert.Equal(t, []string{"a b", " b "}, KShingle([]string{"a  b "}, 3, Whitespace(WhitespaceCollapse)))
+	assert.Equal(t, []string{"ab"}, KShingle([]string{"a \t b\n"}, 2, Whitespace(WhitespaceDrop)))
+}
+
+func Test_KShingle_LineSeparator(t *testing.T) {
+	assert.Equal(t, []string{"ab", "bc", "cd"}, KShingle([]string{"ab", "cd"}, 2))
+	assert.Equal(t, []string{"ab", "b ", " c", "cd"}, KShingle([]string{"ab", "cd"}, 2, LineSeparator(" ")))
+	assert.Equal(t, []string{"ab", "b ", " c", "cd"},
+		KShingle([]string{"ab ", " cd"}, 2, LineSeparator("\n"), Whitespace(WhitespaceCollapse)))
+}
+
+func Test_KShingle_Bytes(t *testing.T) {
+	assert.Equal(t, []string{"hé", "él", "ll", "lo"}, KShingle([]string{"héllo"}, 2))
+	assert.Equal(t, []string{"東京", "京は"}, KShingle([]string{"東京は"}, 2))
+	assert.Equal(t, []string{"h\xc3", "\xc3\xa9", "\xa9l", "ll", "lo"}, KShingle([]string{"héllo"}, 2, ByteShingles(true)))
+	assert.Empty(t, KShingle([]string{"abc"}, 0))
+}
+
+func Test_Shingle64(t *testing.T) {
+	assert.Equal(t, HashShingles(Shingle([]string{aText})), Shingle64([]string{aText}))
+	assert.Equal(t, HashShingles(KShingle([]string{aText}, 9)), KShingle64([]string{aText}, 9))
+	assert.Equal(t, HashShingles(Shingle([]string{dupedText})), Shingle64([]string{dupedText}))
+	assert.Equal(t, HashShingles(KShingle([]string{"東京は東京"}, 2)), KShingle64([]string{"東京は東京"}, 2))
+	assert.Equal(t,
+		HashShingles(KShingle([]string{"héllo"}, 2, ByteShingles(true))),
+		KShingle64([]string{"héllo"}, 2, ByteShingles(true)))
+	assert.Equal(t, []uint64{0xcbf29ce484222325, 0xaf63df4c8601f1a5}, HashShingles([]string{"", "b"}))
+}
+
 func Test_KShingle_DeDuplicates(t *testing.T) {
 	shingles := KShingle([]string{dupedText}, 9)
 
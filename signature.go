@@ -0,0 +1,113 @@
+package lsh
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrSignatureLength is returned when signatures of different lengths are combined,
+// which means that they were built with different hash functions.
+var ErrSignatureLength = errors.New("signatures have different lengths")
+
+// Signature is a MinHash signature of a single set (document),
+// each value is the minimal hash of the set's shingles for the corresponding hash function.
+//
+// Shingles are hashed by content (see ByContent), therefore signature depends only on the shingles
+// of the document itself and can be computed once and stored.
+type Signature []uint64
+
+// NewSignature computes signature of the given shingles of a single document with the given hashers.
+func NewSignature(shingles []string, hashers []*Hasher) Signature {
+	sig := newEmptySignature(len(hashers))
+	for _, sh := range shingles {
+		sig.add(hashShingle(sh), hashers)
+	}
+	return sig
+}
+
+// NewHashedSignature computes signature of the given hashed shingles (see HashShingles) of a single document
+// with the given hashers, it's equal to the signature of the corresponding string shingles.
+func NewHashedSignature(shingles []uint64, hashers []*Hasher) Signature {
+	sig := newEmptySignature(len(hashers))
+	for _, x := range shingles {
+		sig.add(x, hashers)
+	}
+	return sig
+}
+
+// add updates signature with the given hashed shingle.
+func (s Signature) add(x uint64, hashers []*Hasher) {
+	for i, hasher := range hashers {
+		if h := contentHash(hasher, x); s[i] > h {
+			s[i] = h
+		}
+	}
+}
+
+func newEmptySignature(numHashes int) Signature {
+	sig := make(Signature, numHashes)
+	for i := range sig {
+		sig[i] = EmptySet
+	}
+	return sig
+}
+
+// Signature returns signature of the set (document) in the given column of the signature matrix.
+func (sm SignatureMatrix) Signature(column int) Signature {
+	sig := make(Signature, len(sm))
+	for i, row := range sm {
+		sig[i] = row[column]
+	}
+	return sig
+}
+
+// Merge returns signature of the union of the sets represented by this and the other signature.
+func (s Signature) Merge(other Signature) (Signature, error) {
+	if len(s) != len(other) {
+		return nil, ErrSignatureLength
+	}
+	merged := make(Signature, len(s))
+	for i, v := range s {
+		if v < other[i] {
+			merged[i] = v
+		} else {
+			merged[i] = other[i]
+		}
+	}
+	return merged, nil
+}
+
+// Jaccard estimates Jaccard similarity of the sets represented by this and the other signature,
+// i.e. fraction of the hash functions for which both signatures have the same value.
+func (s Signature) Jaccard(other Signature) (float64, error) {
+	if len(s) != len(other) {
+		return 0, ErrSignatureLength
+	}
+	if len(s) == 0 {
+		return 0, nil
+	}
+	var agree int
+	for i, v := range s {
+		// empty sets are not similar to anything
+		if v == other[i] && v != EmptySet {
+			agree++
+		}
+	}
+	return float64(agree) / float64(len(s)), nil
+}
+
+func (s Signature) String() string {
+	var sb strings.Builder
+	for i, v := range s {
+		if i > 0 {
+			checkWriteStringError(sb.WriteString(","))
+		}
+		if v == EmptySet {
+			checkWriteStringError(sb.WriteString("-"))
+		} else {
+			checkWriteStringError(sb.WriteString(strconv.FormatUint(v, 10)))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,65 @@
+package lsh
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewSignature_MatchesMinhashByContent(t *testing.T) {
+	hashers := GenerateHashersSeeded(20, 1)
+
+	minhash := MinhashWithHashers([][]string{aShingles, bShingles}, hashers, ByContent(true))
+
+	assert.Equal(t, minhash.Signature(0), NewSignature(aShingles, hashers))
+	assert.Equal(t, minhash.Signature(1), NewSignature(bShingles, hashers))
+}
+
+func Test_NewHashedSignature(t *testing.T) {
+	hashers := GenerateHashersSeeded(20, 1)
+
+	assert.Equal(t, NewSignature(aShingles, hashers), NewHashedSignature(HashShingles(aShingles), hashers))
+	assert.Equal(t, newEmptySignature(20), NewHashedSignature(nil, hashers))
+}
+
+func Test_NewSignature_Empty(t *testing.T) {
+	sig := NewSignature([]string{}, GenerateHashersSeeded(3, 1))
+
+	assert.Equal(t, Signature{EmptySet, EmptySet, EmptySet}, sig)
+	assert.Equal(t, "-,-,-", sig.String())
+}
+
+func Test_Signature_Merge(t *testing.T) {
+	hashers := GenerateHashersSeeded(20, 1)
+
+	a := NewSignature([]string{"a", "b"}, hashers)
+	b := NewSignature([]string{"c"}, hashers)
+
+	merged, err := a.Merge(b)
+	assert.Nil(t, err)
+	assert.Equal(t, NewSignature([]string{"a", "b", "c"}, hashers), merged)
+
+	_, err = a.Merge(Signature{1})
+	assert.Equal(t, ErrSignatureLength, err)
+}
+
+func Test_Signature_Jaccard(t *testing.T) {
+	hashers := GenerateHashersSeeded(200, 1)
+
+	a := NewSignature(aShingles, hashers)
+
+	j, err := a.Jaccard(NewSignature(aShingles, hashers))
+	assert.Nil(t, err)
+	assert.Equal(t, 1.0, j)
+
+	j, err = a.Jaccard(NewSignature([]string{"nothing in common"}, hashers))
+	assert.Nil(t, err)
+	assert.Equal(t, 0.0, j)
+
+	j, err = a.Jaccard(NewSignature(bShingles, hashers))
+	assert.Nil(t, err)
+	assert.InDelta(t, Jaccard(aShingles, bShingles), j, 0.1)
+
+	_, err = a.Jaccard(Signature{1})
+	assert.Equal(t, ErrSignatureLength, err)
+}
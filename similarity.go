@@ -1,5 +1,7 @@
 package lsh
 
+import "math"
+
 // Jaccard - jaccard index, also known as Intersection over Union
 // and the Jaccard similarity coefficient.
 //
@@ -26,6 +28,36 @@ func Jaccard(a, b []string) float64 {
 	return float64(len(intersetion(setA, setB))) / float64(len(union))
 }
 
+// EstimateJaccard estimates Jaccard similarity of the sets (documents) in columns "a" and "b"
+// of the given signature matrix, i.e. fraction of the rows in which both columns agree.
+// Use JaccardStdError for the accuracy of the estimate.
+func EstimateJaccard(signatureMatrix SignatureMatrix, a, b int) float64 {
+	// columns of the same matrix always have the same length
+	j, _ := signatureMatrix.Signature(a).Jaccard(signatureMatrix.Signature(b))
+	return j
+}
+
+// JaccardStdError returns standard error of the Jaccard similarity "j"
+// estimated with the given number of hash functions.
+//
+// Formulae:
+// SE = sqrt(J * (1 - J) / numHashes)
+//
+func JaccardStdError(j float64, numHashes int) float64 {
+	if numHashes <= 0 {
+		return math.NaN()
+	}
+	return math.Sqrt(j * (1 - j) / float64(numHashes))
+}
+
+// JaccardBounds returns confidence interval of the Jaccard similarity "j"
+// estimated with the given number of hash functions, where "z" is the standard score
+// of the desired confidence level (e.g. 1.96 for 95%).
+func JaccardBounds(j float64, numHashes int, z float64) (float64, float64) {
+	margin := z * JaccardStdError(j, numHashes)
+	return math.Max(0, j-margin), math.Min(1, j+margin)
+}
+
 func intersetion(setA, setB map[string]bool) map[string]bool {
 	intersection := make(map[string]bool)
 	// loop over smaller set
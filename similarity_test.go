@@ -1,6 +1,7 @@
 package lsh
 
 import (
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -10,3 +11,31 @@ func Test_Jaccard(t *testing.T) {
 	assert.Equal(t, 1.0, Jaccard([]string{"a", "b"}, []string{"a", "b"}))
 	assert.Equal(t, 0.5, Jaccard([]string{"a", "b"}, []string{"a"}))
 }
+
+func Test_EstimateJaccard(t *testing.T) {
+	signatureMatrix := SignatureMatrix{
+		{1, 1, 2},
+		{3, 3, 3},
+		{5, 4, 6},
+		{7, 8, 7},
+	}
+
+	assert.Equal(t, 0.5, EstimateJaccard(signatureMatrix, 0, 1))
+	assert.Equal(t, 0.5, EstimateJaccard(signatureMatrix, 0, 2))
+	assert.Equal(t, 0.25, EstimateJaccard(signatureMatrix, 1, 2))
+	assert.Equal(t, 1.0, EstimateJaccard(signatureMatrix, 2, 2))
+}
+
+func Test_JaccardStdError(t *testing.T) {
+	assert.Equal(t, 0.0, JaccardStdError(1, 100))
+	assert.Equal(t, 0.05, JaccardStdError(0.5, 100))
+	assert.True(t, math.IsNaN(JaccardStdError(0.5, 0)))
+
+	lo, hi := JaccardBounds(0.5, 100, 2)
+	assert.InDelta(t, 0.4, lo, 1e-9)
+	assert.InDelta(t, 0.6, hi, 1e-9)
+
+	lo, hi = JaccardBounds(1, 100, 2)
+	assert.Equal(t, 1.0, lo)
+	assert.Equal(t, 1.0, hi)
+}
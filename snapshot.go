@@ -0,0 +1,401 @@
+package lsh
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"math"
+	"sort"
+)
+
+// snapshotMagic identifies snapshots of Search.
+const snapshotMagic = "LSHS"
+
+// snapshotVersion is the version of the snapshot format,
+// it must be incremented on every incompatible change of the format.
+const snapshotVersion = 1
+
+// maxSnapshotBytes limits size of a single byte string (ID, band key, hashers) in the snapshot,
+// so corrupted lengths don't cause huge allocations before the checksum is verified.
+const maxSnapshotBytes = 1 << 30
+
+// ErrChecksum is returned when checksum of the snapshot doesn't match its contents.
+var ErrChecksum = errors.New("snapshot checksum mismatch")
+
+// Snapshot format (all integers are little endian, "uvarint" are variable length unsigned integers):
+//
+//  magic "LSHS" | version uint32
+//  hashers: uvarint length | JSON of []*Hasher
+//  config: bands uint32 | rows uint32 | threshold float64
+//  documents: uvarint count | (uvarint ID length | ID | numHashes * uint64 signature values)...
+//  buckets, for each band: uvarint count | (uvarint key length | key | uvarint count | uvarint document number...)...
+//  checksum: CRC-32 (IEEE) of everything above as uint32
+
+// Save writes snapshot of the search index into the given writer.
+func (s *Search) Save(w io.Writer) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hashers, err := json.Marshal(s.hashers)
+	if err != nil {
+		return fmt.Errorf("can't marshal hashers: %v", err)
+	}
+
+	bw := bufio.NewWriter(w)
+	sw := &snapshotWriter{w: bw, crc: crc32.NewIEEE()}
+
+	sw.write([]byte(snapshotMagic))
+	sw.uint32(snapshotVersion)
+
+	sw.bytes(hashers)
+
+	sw.uint32(uint32(s.bandsNum))
+	sw.uint32(uint32(s.rowsNum))
+	sw.uint64(math.Float64bits(s.threshold))
+
+	// documents are numbered in the order of writing, buckets refer to them by number,
+	// storage iterates in order of IDs, so the same index always produces the same snapshot
+	numbers := make(map[string]uint64, s.index.Len())
+	sw.uvarint(uint64(s.index.Len()))
+	err = s.index.storage.ForEach(func(id string, sig Signature) error {
+		numbers[id] = uint64(len(numbers))
+		sw.bytes([]byte(id))
+		for _, v := range sig {
+			sw.uint64(v)
+		}
+		return sw.err
+	})
+	if err != nil {
+		return err
+	}
+
+	// buckets are collected one band at a time, so only one band is held in memory
+	for b := 0; b < s.index.bands; b++ {
+		band := make(map[string][]uint64)
+		err = s.index.storage.ForEach(func(id string, sig Signature) error {
+			if keys := s.index.bandKeys(sig); keys != nil {
+				band[keys[b]] = append(band[keys[b]], numbers[id])
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		keys := make([]string, 0, len(band))
+		for key := range band {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		sw.uvarint(uint64(len(keys)))
+		for _, key := range keys {
+			sw.bytes([]byte(key))
+			sw.uvarint(uint64(len(band[key])))
+			for _, n := range band[key] {
+				sw.uvarint(n)
+			}
+		}
+	}
+
+	// checksum itself is not a part of the checksum
+	checksum := sw.crc.Sum32()
+	sw.crc = nil
+	sw.uint32(checksum)
+
+	if sw.err != nil {
+		return sw.err
+	}
+	return bw.Flush()
+}
+
+// LoadSearch restores search index from the snapshot written by Search#Save into memory.
+//
+// Snapshot doesn't keep the shingler of the search, so Shingler option must be given again,
+// unless the default one was used. Options which define hashers, bands and documents are taken
+// from the snapshot, giving any of them is an error.
+func LoadSearch(r io.Reader, options ...SearchOption) (*Search, error) {
+	return LoadSearchWithStorage(r, NewMemoryStorage(), options...)
+}
+
+// LoadSearchWithStorage restores search index from the snapshot written by Search#Save
+// into the given empty storage, e.g. DiskStorage, options are the same as of LoadSearch.
+//
+// Documents are written into the storage in batches as they are read, so the snapshot
+// is never held in memory as a whole. If the snapshot turns out to be corrupted,
+// documents already written are removed from the storage.
+func LoadSearchWithStorage(r io.Reader, storage Storage, options ...SearchOption) (*Search, error) {
+	loaded := &Search{}
+	for _, option := range options {
+		option(loaded)
+	}
+	if loaded.hashers != nil || loaded.bandsNum != 0 || loaded.threshold != 0 ||
+		loaded.setsMatrix != nil || loaded.storage != nil {
+		return nil, fmt.Errorf("only Shingler option can be given for snapshot")
+	}
+
+	sr := &snapshotReader{r: bufio.NewReader(r), crc: crc32.NewIEEE()}
+
+	magic := make([]byte, len(snapshotMagic))
+	sr.read(magic)
+	if sr.err == nil && string(magic) != snapshotMagic {
+		return nil, fmt.Errorf("not a snapshot of search")
+	}
+	if version := sr.uint32(); sr.err == nil && version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported version of snapshot: %d", version)
+	}
+
+	var hashers []*Hasher
+	if data := sr.bytes(); sr.err == nil {
+		if err := json.Unmarshal(data, &hashers); err != nil {
+			return nil, fmt.Errorf("can't unmarshal hashers: %v", err)
+		}
+	}
+
+	bands := int(sr.uint32())
+	rows := int(sr.uint32())
+	threshold := math.Float64frombits(sr.uint64())
+	if sr.err != nil {
+		return nil, sr.err
+	}
+
+	if storage.Len() > 0 {
+		return nil, fmt.Errorf("storage already holds %d documents", storage.Len())
+	}
+	if bands*rows > len(hashers) {
+		return nil, fmt.Errorf("%d bands of %d rows need %d hashes, got %d", bands, rows, bands*rows, len(hashers))
+	}
+	index, err := NewLSHIndexWithStorage(bands, rows, storage)
+	if err != nil {
+		return nil, fmt.Errorf("invalid band configuration: %v", err)
+	}
+
+	if err := loadSnapshotDocuments(sr, index, len(hashers)); err != nil {
+		if clearErr := clearStorage(index); clearErr != nil {
+			return nil, fmt.Errorf("%v, can't remove loaded documents: %v", err, clearErr)
+		}
+		return nil, err
+	}
+	if err := storeHashers(storage, hashers); err != nil {
+		return nil, err
+	}
+
+	loaded.hashers = hashers
+	loaded.bandsNum = bands
+	loaded.rowsNum = rows
+	loaded.threshold = threshold
+	loaded.storage = index.storage
+	loaded.index = index
+	return loaded, nil
+}
+
+// loadSnapshotDocuments reads documents and buckets of the snapshot and writes documents into the index storage,
+// buckets of the snapshot are verified against the ones computed from signatures via per band digests,
+// so neither of them is kept in memory.
+func loadSnapshotDocuments(sr *snapshotReader, index *LSHIndex, numHashes int) error {
+	// order independent digests of (document number, band key) pairs of each band
+	expected := make([]uint64, index.bands)
+
+	numDocs := sr.uvarint()
+	updates := make([]*StorageUpdate, 0, addBatchSize)
+	for i := uint64(0); i < numDocs && sr.err == nil; i++ {
+		id := string(sr.bytes())
+		sig := make(Signature, numHashes)
+		for k := range sig {
+			sig[k] = sr.uint64()
+		}
+		keys := index.bandKeys(sig)
+		for b, key := range keys {
+			expected[b] += bucketEntryDigest(i, key)
+		}
+		updates = append(updates, &StorageUpdate{ID: id, Signature: sig, Keys: keys})
+		if len(updates) == addBatchSize {
+			if err := index.storage.Apply(updates); err != nil {
+				return err
+			}
+			updates = updates[:0]
+		}
+	}
+	if sr.err == nil && len(updates) > 0 {
+		if err := index.storage.Apply(updates); err != nil {
+			return err
+		}
+	}
+
+	for b := 0; b < index.bands && sr.err == nil; b++ {
+		var digest uint64
+		numBuckets := sr.uvarint()
+		for i := uint64(0); i < numBuckets && sr.err == nil; i++ {
+			key := string(sr.bytes())
+			numIDs := sr.uvarint()
+			for k := uint64(0); k < numIDs && sr.err == nil; k++ {
+				n := sr.uvarint()
+				if sr.err == nil && n >= numDocs {
+					return fmt.Errorf("bucket refers to unknown document %d", n)
+				}
+				digest += bucketEntryDigest(n, key)
+			}
+		}
+		if sr.err == nil && digest != expected[b] {
+			return fmt.Errorf("buckets of band %d don't match signatures of documents", b)
+		}
+	}
+
+	checksum := sr.crc.Sum32()
+	sr.crc = nil
+	stored := sr.uint32()
+	if sr.err != nil {
+		return sr.err
+	}
+	if checksum != stored {
+		return ErrChecksum
+	}
+	if uint64(index.storage.Len()) != numDocs {
+		return fmt.Errorf("snapshot holds %d documents, %d of them are unique", numDocs, index.storage.Len())
+	}
+	return nil
+}
+
+// bucketEntryDigest returns hash of the document number and the band key of its bucket,
+// sum of such hashes doesn't depend on the order of entries.
+func bucketEntryDigest(n uint64, key string) uint64 {
+	h := uint64(fnvOffset64)
+	for i := uint(0); i < 64; i += 8 {
+		h ^= (n >> i) & 0xff
+		h *= fnvPrime64
+	}
+	return fnvString(h, key)
+}
+
+// clearStorage removes all documents from the storage of the index.
+func clearStorage(index *LSHIndex) error {
+	var updates []*StorageUpdate
+	err := index.storage.ForEach(func(id string, sig Signature) error {
+		updates = append(updates, &StorageUpdate{ID: id, OldKeys: index.bandKeys(sig)})
+		return nil
+	})
+	if err != nil || len(updates) == 0 {
+		return err
+	}
+	return index.storage.Apply(updates)
+}
+
+// snapshotWriter writes values of the snapshot and updates its checksum,
+// it remembers the first error, so the rest of writes are skipped.
+type snapshotWriter struct {
+	w   io.Writer
+	crc hash.Hash32
+	err error
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (sw *snapshotWriter) write(p []byte) {
+	if sw.err != nil {
+		return
+	}
+	if sw.crc != nil {
+		// hash.Hash never returns an error on write
+		_, _ = sw.crc.Write(p)
+	}
+	_, sw.err = sw.w.Write(p)
+}
+
+func (sw *snapshotWriter) uint32(v uint32) {
+	binary.LittleEndian.PutUint32(sw.buf[:4], v)
+	sw.write(sw.buf[:4])
+}
+
+func (sw *snapshotWriter) uint64(v uint64) {
+	binary.LittleEndian.PutUint64(sw.buf[:8], v)
+	sw.write(sw.buf[:8])
+}
+
+func (sw *snapshotWriter) uvarint(v uint64) {
+	n := binary.PutUvarint(sw.buf[:], v)
+	sw.write(sw.buf[:n])
+}
+
+func (sw *snapshotWriter) bytes(p []byte) {
+	sw.uvarint(uint64(len(p)))
+	sw.write(p)
+}
+
+// snapshotReader reads values of the snapshot and updates its checksum,
+// it remembers the first error, so the rest of reads return zero values.
+type snapshotReader struct {
+	r   *bufio.Reader
+	crc hash.Hash32
+	err error
+	buf [8]byte
+}
+
+func (sr *snapshotReader) read(p []byte) {
+	if sr.err != nil {
+		return
+	}
+	if _, err := io.ReadFull(sr.r, p); err != nil {
+		sr.err = fmt.Errorf("can't read snapshot: %v", err)
+		return
+	}
+	if sr.crc != nil {
+		// hash.Hash never returns an error on write
+		_, _ = sr.crc.Write(p)
+	}
+}
+
+func (sr *snapshotReader) uint32() uint32 {
+	sr.read(sr.buf[:4])
+	if sr.err != nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(sr.buf[:4])
+}
+
+func (sr *snapshotReader) uint64() uint64 {
+	sr.read(sr.buf[:8])
+	if sr.err != nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(sr.buf[:8])
+}
+
+func (sr *snapshotReader) uvarint() uint64 {
+	if sr.err != nil {
+		return 0
+	}
+	var v uint64
+	var shift uint
+	for i := 0; i < binary.MaxVarintLen64; i++ {
+		sr.read(sr.buf[:1])
+		if sr.err != nil {
+			return 0
+		}
+		b := sr.buf[0]
+		v |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			return v
+		}
+		shift += 7
+	}
+	sr.err = fmt.Errorf("can't read snapshot: malformed varint")
+	return 0
+}
+
+func (sr *snapshotReader) bytes() []byte {
+	n := sr.uvarint()
+	if sr.err != nil {
+		return nil
+	}
+	if n > maxSnapshotBytes {
+		sr.err = fmt.Errorf("can't read snapshot: too long value of %d bytes", n)
+		return nil
+	}
+	p := make([]byte, n)
+	sr.read(p)
+	return p
+}
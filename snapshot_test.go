@@ -0,0 +1,106 @@
+package lsh
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newSnapshotSearch(t *testing.T) *Search {
+	search, err := NewSearch(HashersNum(50), BandsNum(10))
+	assert.Nil(t, err)
+	assert.Nil(t, search.Add("a", aShingles))
+	assert.Nil(t, search.Add("b", bShingles))
+	assert.Nil(t, search.Add("jim", Shingle([]string{jimText})))
+	assert.Nil(t, search.Add("empty", []string{}))
+	return search
+}
+
+func Test_Search_SaveLoad(t *testing.T) {
+	search := newSnapshotSearch(t)
+
+	var buf bytes.Buffer
+	assert.Nil(t, search.Save(&buf))
+
+	loaded, err := LoadSearch(bytes.NewReader(buf.Bytes()))
+	assert.Nil(t, err)
+
+	assert.Equal(t, search.Len(), loaded.Len())
+	assert.Equal(t, search.bandsNum, loaded.bandsNum)
+	assert.Equal(t, search.rowsNum, loaded.rowsNum)
+	assert.Equal(t, search.storage, loaded.storage)
+	assert.Equal(t, find(t, search, aText), find(t, loaded, aText))
+
+	// loaded search is fully functional
+	assert.Nil(t, loaded.Add("a copy", aShingles))
+	assert.Len(t, find(t, loaded, aText), 2)
+
+	// the same index produces the same snapshot
+	var again bytes.Buffer
+	assert.Nil(t, search.Save(&again))
+	assert.Equal(t, buf.Bytes(), again.Bytes())
+}
+
+func Test_LoadSearch_corrupted(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, newSnapshotSearch(t).Save(&buf))
+	snapshot := buf.Bytes()
+
+	// flip a bit in signatures
+	corrupted := append([]byte(nil), snapshot...)
+	corrupted[len(corrupted)/2] ^= 1
+	_, err := LoadSearch(bytes.NewReader(corrupted))
+	assert.NotNil(t, err)
+
+	// truncated
+	_, err = LoadSearch(bytes.NewReader(snapshot[:len(snapshot)-10]))
+	assert.NotNil(t, err)
+
+	// checksum
+	corrupted = append([]byte(nil), snapshot...)
+	corrupted[len(corrupted)-1] ^= 1
+	_, err = LoadSearch(bytes.NewReader(corrupted))
+	assert.Equal(t, ErrChecksum, err)
+
+	// documents loaded before the checksum mismatch is found are removed
+	storage := NewMemoryStorage()
+	_, err = LoadSearchWithStorage(bytes.NewReader(corrupted), storage)
+	assert.Equal(t, ErrChecksum, err)
+	assert.Equal(t, 0, storage.Len())
+	loaded, err := LoadSearchWithStorage(bytes.NewReader(snapshot), storage)
+	assert.Nil(t, err)
+	assert.Equal(t, find(t, newSnapshotSearch(t), aText), find(t, loaded, aText))
+
+	// not a snapshot
+	_, err = LoadSearch(bytes.NewReader([]byte("not a snapshot at all")))
+	assert.NotNil(t, err)
+
+	// unsupported version
+	corrupted = append([]byte(nil), snapshot...)
+	corrupted[4] = 99
+	_, err = LoadSearch(bytes.NewReader(corrupted))
+	assert.NotNil(t, err)
+}
+
+func Test_LoadSearch_Shingler(t *testing.T) {
+	kShingle := func(text string) []string {
+		return KShingle([]string{text}, 5)
+	}
+	search, err := NewSearch(HashersNum(50), BandsNum(10), Shingler(kShingle))
+	assert.Nil(t, err)
+	assert.Nil(t, search.AddText("a", aText))
+
+	var buf bytes.Buffer
+	assert.Nil(t, search.Save(&buf))
+
+	loaded, err := LoadSearch(bytes.NewReader(buf.Bytes()), Shingler(kShingle))
+	assert.Nil(t, err)
+	results := find(t, loaded, aText)
+	assert.NotEmpty(t, results)
+	assert.Equal(t, 1.0, results[0].Score)
+
+	// configuration of the index comes from the snapshot
+	_, err = LoadSearch(bytes.NewReader(buf.Bytes()), BandsNum(5))
+	assert.NotNil(t, err)
+}
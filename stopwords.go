@@ -0,0 +1,145 @@
+package lsh
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/zoomio/stopwords"
+)
+
+// StopWords is a set of lowercase stop words, which anchor shingles produced by Shingle.
+type StopWords map[string]bool
+
+// NewStopWords returns set of the given stop words, words are lowercased.
+func NewStopWords(words ...string) StopWords {
+	sw := make(StopWords, len(words))
+	for _, w := range words {
+		sw[strings.ToLower(w)] = true
+	}
+	return sw
+}
+
+// IsStopWord tells whether the given lowercase word is a stop word.
+func (sw StopWords) IsStopWord(word string) bool {
+	return sw[word]
+}
+
+// englishLanguage is the language of stop words used by Shingle by default.
+const englishLanguage = "en"
+
+// languageWords contains most common words of the supported languages by their ISO 639-1 codes,
+// they serve as stop words and are used for language detection,
+// English stop words for shingling come from github.com/zoomio/stopwords.
+var languageWords = map[string]string{
+	englishLanguage: `a about after all also an and are as at be because been but by can could do for from
+		had has have he her his how i if in into is it its more my no not of on one or our out she so
+		than that the their them then there these they this to up was we were what when which who will
+		with would you your`,
+	"de": `aber alle als also am an auch auf aus bei bin bis bist da damit dann das dass dem den denn der
+		des die dies diese dieser doch dort du durch ein eine einem einen einer eines er es für hat hatte
+		ich ihr ihre im in ist ja kann kein keine man mich mir mit nach nicht noch nur ob oder ohne sehr
+		sich sie sind so über um und uns unter vom von vor war waren was weil wenn werden wie wieder will
+		wir wird wo zu zum zur`,
+	"es": `a al algo algunos ante antes como con contra cual cuando de del desde donde durante e el ella
+		ellos en entre era es esa ese eso esta está estaba este esto fue ha había han hasta hay la las le
+		les lo los más me mi muy nada ni no nos o otra otro para pero poco por porque que quien se sea
+		ser si sin sobre son su sus también te tiene todo tu un una uno y ya yo`,
+	"fr": `à au aussi aux avec avait avoir ce cela ces cet cette comme dans de des du elle elles en est et
+		été être eu il ils je la le les leur lui mais me même mes moi mon ne nos notre nous on ont ou où
+		par pas plus pour qu que qui sa sans se ses si son sont sur ta te tes toi ton tous tout très tu un
+		une vos votre vous y`,
+	"it": `a ad al alla alle anche ci come con cui da dal dalla dei del della delle di dove e è ed era gli
+		ha hanno i il in io la le lei li lo loro lui ma mi mio ne negli nel nella non noi o per perché più
+		quale quando quella quello questa questo se sei si sia sono su sua sue suo tra tu tutti tutto un
+		una uno vi`,
+	"nl": `aan al als bij dan dat de der deze die dit doch door dus een en er ge geen had heb hebben heeft
+		hem het hier hij hoe hun ik in is ja je kan maar me men met mij na naar niet nog nu of om omdat
+		ons ook op over te tot toch u uit van veel voor want was wat we wel werd wie wij wordt zal ze zei
+		zich zij zijn zo zonder`,
+	"pt": `a ao aos as até com como da das de dela dele do dos e é ela ele eles em entre era essa esse
+		esta está este eu foi foram há isso isto já lhe mais mas me mesmo meu minha muito na nas nem no
+		nos o os ou para pela pelo por quando que quem se sem ser seu sua são também te tem um uma você`,
+	"ru": `а без бы был была были было в вам вас весь во вот все всё вы где да для до его ее её если есть
+		еще ещё же за и из или им их к как когда кто ли меня мне мы на над не него нее неё нет ни них но
+		о об он она они оно от по под при с со так также там то тоже только у уже чем что чтобы эта эти
+		это я`,
+}
+
+// languageStopWords contains sets of the most common words of the supported languages,
+// they are stop words for all the languages except English, for which they are only used in detection.
+var languageStopWords = make(map[string]StopWords, len(languageWords))
+
+// englishStopWords contains English stop words for shingling.
+var englishStopWords StopWords
+
+func init() {
+	for lang, words := range languageWords {
+		languageStopWords[lang] = NewStopWords(strings.Fields(words)...)
+	}
+	englishStopWords = NewStopWords(stopwords.Slice()...)
+}
+
+// Languages returns sorted ISO 639-1 codes of the languages with built in stop words.
+func Languages() []string {
+	langs := make([]string, 0, len(languageWords))
+	for lang := range languageWords {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// LanguageStopWords returns built in stop words of the language with the given ISO 639-1 code,
+// e.g. "de" for German, returned set is a copy, so it can be modified.
+func LanguageStopWords(lang string) (StopWords, error) {
+	sw, err := languageStopWordsOf(lang)
+	if err != nil {
+		return nil, err
+	}
+	cp := make(StopWords, len(sw))
+	for w := range sw {
+		cp[w] = true
+	}
+	return cp, nil
+}
+
+// languageStopWordsOf returns shared built in stop words of the language, which must not be modified.
+func languageStopWordsOf(lang string) (StopWords, error) {
+	if lang == englishLanguage {
+		return englishStopWords, nil
+	}
+	sw, ok := languageStopWords[lang]
+	if !ok {
+		return nil, fmt.Errorf("no stop words for language %q, supported languages are %v", lang, Languages())
+	}
+	return sw, nil
+}
+
+// DetectLanguage returns ISO 639-1 code of the language of the given words,
+// which is the language with the largest number of its most common words among them,
+// empty string is returned if none of the words is known.
+func DetectLanguage(words []string) string {
+	counts := make(map[string]int, len(languageStopWords))
+	for _, w := range words {
+		w = strings.ToLower(w)
+		for lang, sw := range languageStopWords {
+			if sw[w] {
+				counts[lang]++
+			}
+		}
+	}
+
+	detected := ""
+	for _, lang := range Languages() {
+		if counts[lang] > counts[detected] {
+			detected = lang
+		}
+	}
+	return detected
+}
+
+// isEnglishStopWord tells whether the given lowercase word is an English stop word.
+func isEnglishStopWord(word string) bool {
+	return stopwords.IsStopWord(word)
+}
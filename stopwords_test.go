@@ -0,0 +1,58 @@
+package lsh
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	deText = "Der Hund läuft durch den Park und die Kinder spielen mit dem Ball."
+	ruText = "Мы пошли в парк, и дети играли с мячом до вечера."
+)
+
+func Test_LanguageStopWords(t *testing.T) {
+	de, err := LanguageStopWords("de")
+	assert.Nil(t, err)
+	assert.True(t, de.IsStopWord("und"))
+	assert.False(t, de.IsStopWord("hund"))
+
+	en, err := LanguageStopWords("en")
+	assert.Nil(t, err)
+	assert.True(t, en.IsStopWord("the"))
+
+	// built in stop words can't be modified through the returned set
+	delete(de, "und")
+	de, err = LanguageStopWords("de")
+	assert.Nil(t, err)
+	assert.True(t, de.IsStopWord("und"))
+
+	_, err = LanguageStopWords("xx")
+	assert.NotNil(t, err)
+
+	assert.Contains(t, Languages(), "ru")
+}
+
+func Test_DetectLanguage(t *testing.T) {
+	tokenizer := NewWordTokenizer()
+	assert.Equal(t, "de", DetectLanguage(tokenizer.Tokenize(deText)))
+	assert.Equal(t, "ru", DetectLanguage(tokenizer.Tokenize(ruText)))
+	assert.Equal(t, "en", DetectLanguage(tokenizer.Tokenize(aText)))
+	assert.Equal(t, "", DetectLanguage([]string{"xyz"}))
+}
+
+func Test_Shingle_StopWords(t *testing.T) {
+	de, err := LanguageStopWords("de")
+	assert.Nil(t, err)
+	assert.Equal(t,
+		[]string{"Der Hund läuft", "durch den Park", "den Park und", "und die Kinder", "die Kinder spielen", "mit dem Ball"},
+		Shingle([]string{deText}, WithStopWords(de)))
+
+	assert.Equal(t,
+		Shingle([]string{deText}, WithStopWords(de)),
+		Shingle([]string{deText}, DetectStopWordsLanguage(true)))
+
+	assert.Equal(t,
+		[]string{"Hund läuft durch", "Kinder spielen mit"},
+		Shingle([]string{deText}, WithStopWords(NewStopWords("Hund", "Kinder"))))
+}
@@ -0,0 +1,171 @@
+package lsh
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Storage is a backend of LSHIndex, which keeps signatures of the documents and band buckets.
+//
+// LSHIndex computes band keys and serialises its updates under its own lock,
+// so implementations don't need to be safe for concurrent use.
+type Storage interface {
+	// Init prepares storage for index with the given number of bands and rows in each band,
+	// returns an error if storage already holds index with a different configuration.
+	Init(bands, rows int) error
+
+	// Apply applies the given updates in order,
+	// on-disk implementations apply all of them in a single transaction, so either all or none are stored.
+	Apply(updates []*StorageUpdate) error
+
+	// Get returns signature of the document, false is returned if there is no such document.
+	Get(id string) (Signature, bool, error)
+
+	// Bucket returns IDs of the documents in the bucket with the given key of the given band.
+	Bucket(band int, key string) ([]string, error)
+
+	// ForEach calls given function for each stored document in order of IDs,
+	// iteration stops on the first error returned by the function.
+	ForEach(fn func(id string, sig Signature) error) error
+
+	// Len returns number of stored documents.
+	Len() int
+
+	// Property returns value of the index property with the given key, nil is returned if it's not set.
+	Property(key string) ([]byte, error)
+
+	// SetProperty sets value of the index property with the given key,
+	// e.g. hash functions signatures of the documents are computed with.
+	SetProperty(key string, value []byte) error
+
+	// Close releases resources held by the storage.
+	Close() error
+}
+
+// StorageUpdate is a change of a single document in Storage,
+// the document is removed from the buckets with OldKeys, then, unless Signature is nil,
+// its signature is stored and it's added into the buckets with Keys, otherwise its signature is removed.
+// The i-th key belongs to the i-th band.
+type StorageUpdate struct {
+	ID        string
+	Signature Signature
+	Keys      []string
+	OldKeys   []string
+}
+
+// memoryStorage keeps signatures and buckets in maps.
+type memoryStorage struct {
+	// number of rows in each band
+	rows int
+
+	// buckets of each band, which map band key to the set of document IDs
+	buckets []map[string]map[string]bool
+
+	// signatures of the documents by document ID
+	signatures map[string]Signature
+
+	// properties of the index by key
+	properties map[string][]byte
+}
+
+// NewMemoryStorage creates storage, which keeps everything in memory.
+func NewMemoryStorage() Storage {
+	return &memoryStorage{
+		signatures: make(map[string]Signature),
+		properties: make(map[string][]byte),
+	}
+}
+
+func (ms *memoryStorage) Init(bands, rows int) error {
+	if ms.buckets != nil {
+		if len(ms.buckets) != bands {
+			return fmt.Errorf("memory storage holds index with bands %d, got %d", len(ms.buckets), bands)
+		}
+		if ms.rows != rows {
+			return fmt.Errorf("memory storage holds index with rows %d, got %d", ms.rows, rows)
+		}
+		return nil
+	}
+	ms.rows = rows
+	ms.buckets = make([]map[string]map[string]bool, bands)
+	for b := 0; b < bands; b++ {
+		ms.buckets[b] = make(map[string]map[string]bool)
+	}
+	return nil
+}
+
+func (ms *memoryStorage) Apply(updates []*StorageUpdate) error {
+	for _, u := range updates {
+		for b, key := range u.OldKeys {
+			bucket := ms.buckets[b][key]
+			delete(bucket, u.ID)
+			// drop empty buckets, so they don't pile up
+			if len(bucket) == 0 {
+				delete(ms.buckets[b], key)
+			}
+		}
+		if u.Signature == nil {
+			delete(ms.signatures, u.ID)
+			continue
+		}
+		ms.signatures[u.ID] = u.Signature
+		for b, key := range u.Keys {
+			bucket, ok := ms.buckets[b][key]
+			if !ok {
+				bucket = make(map[string]bool)
+				ms.buckets[b][key] = bucket
+			}
+			bucket[u.ID] = true
+		}
+	}
+	return nil
+}
+
+func (ms *memoryStorage) Get(id string) (Signature, bool, error) {
+	sig, ok := ms.signatures[id]
+	if !ok {
+		return nil, false, nil
+	}
+	// copy, so callers can't modify stored signature
+	return append(Signature(nil), sig...), true, nil
+}
+
+func (ms *memoryStorage) Bucket(band int, key string) ([]string, error) {
+	bucket := ms.buckets[band][key]
+	ids := make([]string, 0, len(bucket))
+	for id := range bucket {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (ms *memoryStorage) ForEach(fn func(id string, sig Signature) error) error {
+	ids := make([]string, 0, len(ms.signatures))
+	for id := range ms.signatures {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if err := fn(id, ms.signatures[id]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ms *memoryStorage) Len() int {
+	return len(ms.signatures)
+}
+
+func (ms *memoryStorage) Property(key string) ([]byte, error) {
+	return ms.properties[key], nil
+}
+
+func (ms *memoryStorage) SetProperty(key string, value []byte) error {
+	ms.properties[key] = append([]byte(nil), value...)
+	return nil
+}
+
+func (ms *memoryStorage) Close() error {
+	return nil
+}
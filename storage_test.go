@@ -0,0 +1,37 @@
+package lsh
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MemoryStorage(t *testing.T) {
+	hashers := GenerateHashersSeeded(20, 1)
+	storage := NewMemoryStorage()
+
+	idx, err := NewLSHIndexWithStorage(10, 2, storage)
+	assert.Nil(t, err)
+	sig := NewSignature(aShingles, hashers)
+	assert.Nil(t, idx.Add("a", sig))
+
+	// documents survive re-initialisation with the same configuration
+	idx, err = NewLSHIndexWithStorage(10, 2, storage)
+	assert.Nil(t, err)
+	found, err := idx.Query(sig)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a"}, found)
+
+	_, err = NewLSHIndexWithStorage(5, 4, storage)
+	assert.NotNil(t, err)
+	_, err = NewLSHIndexWithStorage(10, 1, storage)
+	assert.NotNil(t, err)
+
+	// stored signature can't be modified through the returned one
+	got, ok, err := storage.Get("a")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	got[0] = EmptySet
+	stored, _, _ := storage.Get("a")
+	assert.Equal(t, sig, stored)
+}
@@ -0,0 +1,149 @@
+package lsh
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Document is a set of shingles identified by ID,
+// if shingles are nil, they are produced from the text of the document by the consumer.
+type Document struct {
+	ID       string
+	Text     string
+	Shingles []string
+}
+
+// DocumentReader reads documents one by one,
+// Read returns io.EOF when there are no more documents.
+type DocumentReader interface {
+	Read() (*Document, error)
+}
+
+// DocumentReaderFunc allows to use an ordinary function as DocumentReader.
+type DocumentReaderFunc func() (*Document, error)
+
+// Read implements DocumentReader.
+func (f DocumentReaderFunc) Read() (*Document, error) {
+	return f()
+}
+
+// NewChannelReader returns DocumentReader of the documents
+// received from the given channel until it's closed.
+func NewChannelReader(documents <-chan *Document) DocumentReader {
+	return DocumentReaderFunc(func() (*Document, error) {
+		doc, ok := <-documents
+		if !ok {
+			return nil, io.EOF
+		}
+		return doc, nil
+	})
+}
+
+// jsonLinesRecord is a record of JSON Lines input,
+// shingles are used as is if they are present, otherwise they are produced from text.
+type jsonLinesRecord struct {
+	ID       string   `json:"id"`
+	Text     string   `json:"text"`
+	Shingles []string `json:"shingles"`
+}
+
+// NewJSONLinesReader returns DocumentReader of JSON Lines records,
+// which have "id" and either "shingles" or "text" field, e.g. {"id": "1", "text": "..."},
+// text is turned into shingles by the given function, if it's nil, text is left to the consumer,
+// e.g. Search#AddFrom uses Shingler of the search.
+// Records are decoded one by one, so the input is never loaded into memory as a whole.
+func NewJSONLinesReader(r io.Reader, shingle func(text string) []string) DocumentReader {
+	decoder := json.NewDecoder(r)
+	line := 0
+	return DocumentReaderFunc(func() (*Document, error) {
+		var record jsonLinesRecord
+		if err := decoder.Decode(&record); err != nil {
+			if err == io.EOF {
+				return nil, err
+			}
+			return nil, fmt.Errorf("can't decode record %d: %v", line+1, err)
+		}
+		line++
+		if record.ID == "" {
+			return nil, fmt.Errorf("record %d has no id", line)
+		}
+		if record.Shingles == nil && shingle != nil {
+			record.Shingles = shingle(record.Text)
+		}
+		return &Document{ID: record.ID, Text: record.Text, Shingles: record.Shingles}, nil
+	})
+}
+
+// MinhashStream computes signatures of the documents from the given reader one by one
+// and passes them to fn, so only a single document is kept in memory at a time,
+// text of the documents without shingles is turned into shingles by Shingle.
+// It stops at the first error returned by the reader or fn.
+func MinhashStream(reader DocumentReader, hashers []*Hasher, fn func(id string, sig Signature) error) error {
+	return minhashStream(reader, hashers, defaultShingleText, fn)
+}
+
+// defaultShingleText turns text into shingles by Shingle with default options.
+func defaultShingleText(text string) []string {
+	return Shingle([]string{text})
+}
+
+func minhashStream(reader DocumentReader, hashers []*Hasher, shingle func(text string) []string,
+	fn func(id string, sig Signature) error) error {
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		shingles := doc.Shingles
+		if shingles == nil {
+			shingles = shingle(doc.Text)
+		}
+		if err := fn(doc.ID, NewSignature(shingles, hashers)); err != nil {
+			return err
+		}
+	}
+}
+
+// AddFrom adds documents from the given reader into the index with signatures computed by the given hashers,
+// text of the documents without shingles is turned into shingles by Shingle,
+// documents are added into storage in batches, returns number of added documents.
+func (idx *LSHIndex) AddFrom(reader DocumentReader, hashers []*Hasher) (int, error) {
+	return addFrom(reader, hashers, defaultShingleText, idx.AddAll)
+}
+
+// addFrom reads documents from the given reader, computes their signatures
+// and passes them to the add function in batches of addBatchSize documents.
+func addFrom(reader DocumentReader, hashers []*Hasher, shingle func(text string) []string,
+	add func(ids []string, sigs []Signature) error) (int, error) {
+	added := 0
+	ids := make([]string, 0, addBatchSize)
+	sigs := make([]Signature, 0, addBatchSize)
+	flush := func() error {
+		if len(ids) == 0 {
+			return nil
+		}
+		if err := add(ids, sigs); err != nil {
+			return err
+		}
+		added += len(ids)
+		ids, sigs = ids[:0], sigs[:0]
+		return nil
+	}
+
+	err := minhashStream(reader, hashers, shingle, func(id string, sig Signature) error {
+		ids = append(ids, id)
+		sigs = append(sigs, sig)
+		if len(ids) == addBatchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return added, err
+	}
+	return added, flush()
+}
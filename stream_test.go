@@ -0,0 +1,74 @@
+package lsh
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MinhashStream_JSONLines(t *testing.T) {
+	hashers := GenerateHashersSeeded(20, 1)
+	input := `{"id": "jim", "text": "` + jimText + `"}
+{"id": "a", "shingles": ["` + strings.Join(aShingles, `", "`) + `"]}
+`
+
+	var ids []string
+	var sigs []Signature
+	err := MinhashStream(NewJSONLinesReader(strings.NewReader(input), nil), hashers, func(id string, sig Signature) error {
+		ids = append(ids, id)
+		sigs = append(sigs, sig)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"jim", "a"}, ids)
+	assert.Equal(t, []Signature{
+		NewSignature(Shingle([]string{jimText}), hashers),
+		NewSignature(aShingles, hashers),
+	}, sigs)
+}
+
+func Test_MinhashStream_invalid(t *testing.T) {
+	hashers := GenerateHashersSeeded(20, 1)
+	noop := func(string, Signature) error { return nil }
+
+	err := MinhashStream(NewJSONLinesReader(strings.NewReader(`{"id": "a"}`+"\n{"), nil), hashers, noop)
+	assert.NotNil(t, err)
+
+	err = MinhashStream(NewJSONLinesReader(strings.NewReader(`{"text": "no id"}`), nil), hashers, noop)
+	assert.NotNil(t, err)
+}
+
+func Test_LSHIndex_AddFrom(t *testing.T) {
+	hashers := GenerateHashersSeeded(20, 1)
+	idx, err := NewLSHIndex(10, 2)
+	assert.Nil(t, err)
+
+	documents := make(chan *Document)
+	go func() {
+		documents <- &Document{ID: "a", Shingles: aShingles}
+		documents <- &Document{ID: "b", Shingles: bShingles}
+		close(documents)
+	}()
+
+	added, err := idx.AddFrom(NewChannelReader(documents), hashers)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, added)
+
+	found, err := idx.Query(NewSignature(aShingles, hashers))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a"}, found)
+}
+
+func Test_Search_AddFrom(t *testing.T) {
+	search, err := NewSearch(HashersNum(100))
+	assert.Nil(t, err)
+
+	added, err := search.AddFrom(NewJSONLinesReader(strings.NewReader(`{"id": "jim", "text": "`+jimText+`"}`), nil))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, added)
+
+	results := find(t, search, jimText)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "jim", results[0].ID)
+}
@@ -0,0 +1,127 @@
+package lsh
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Tokenizer splits text into tokens, e.g. words.
+type Tokenizer interface {
+	Tokenize(text string) []string
+}
+
+// TokenizerFunc allows to use an ordinary function as Tokenizer.
+type TokenizerFunc func(text string) []string
+
+// Tokenize implements Tokenizer.
+func (f TokenizerFunc) Tokenize(text string) []string {
+	return f(text)
+}
+
+// codeTokens matches string literals, identifiers, numbers,
+// common multi-character operators and any other single non-space character.
+var codeTokens = regexp.MustCompile(`"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'|` + "`[^`]*`" +
+	`|[\p{L}_][\p{L}\p{N}_]*|\p{N}[\p{L}\p{N}_.]*` +
+	`|==|!=|<=|>=|&&|\|\||:=|->|=>|\+\+|--|<<|>>|\S`)
+
+// NewWhitespaceTokenizer returns Tokenizer which splits text on whitespace
+// and removes punctuation marks from the tokens, this is the default tokenizer of Shingle.
+func NewWhitespaceTokenizer() Tokenizer {
+	return TokenizerFunc(func(text string) []string {
+		fields := strings.Fields(text)
+		for i, field := range fields {
+			fields[i] = removePunctuationMarks(field)
+		}
+		return fields
+	})
+}
+
+// NewRegexpTokenizer returns Tokenizer which produces all matches of the given regular expression as tokens.
+func NewRegexpTokenizer(re *regexp.Regexp) Tokenizer {
+	return TokenizerFunc(func(text string) []string {
+		return re.FindAllString(text, -1)
+	})
+}
+
+// NewCodeTokenizer returns Tokenizer of source code,
+// which keeps identifiers, numbers and string literals whole
+// and produces operators and punctuation as separate tokens.
+func NewCodeTokenizer() Tokenizer {
+	return NewRegexpTokenizer(codeTokens)
+}
+
+// NewWordTokenizer returns Tokenizer which splits text on word boundaries,
+// following simplified rules of Unicode text segmentation (UAX #29).
+// Letters, digits and connectors like "_" form words, combining marks stay with the preceding character,
+// apostrophes, periods and colons are kept inside words, e.g. "don't" or "3.14",
+// runs of Katakana form words, while every ideograph and Hiragana character is a word on its own,
+// so CJK text is split into characters. Whitespace, punctuation and symbols are dropped.
+func NewWordTokenizer() Tokenizer {
+	return TokenizerFunc(tokenizeWords)
+}
+
+// wordClass is a class of a character in word segmentation.
+type wordClass int
+
+const (
+	otherClass wordClass = iota
+	letterClass
+	katakanaClass
+	ideographClass
+	markClass
+	midLetterClass
+)
+
+func classify(r rune) wordClass {
+	switch {
+	case unicode.In(r, unicode.Han, unicode.Hiragana):
+		return ideographClass
+	case unicode.Is(unicode.Katakana, r) || r == 'ー':
+		return katakanaClass
+	case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Pc, r):
+		return letterClass
+	case unicode.Is(unicode.M, r):
+		return markClass
+	case r == '\'' || r == '’' || r == '.' || r == ':':
+		return midLetterClass
+	}
+	return otherClass
+}
+
+func tokenizeWords(text string) []string {
+	tokens := make([]string, 0)
+	runes := []rune(text)
+
+	start := -1
+	class := otherClass
+	flush := func(end int) {
+		if start >= 0 {
+			tokens = append(tokens, string(runes[start:end]))
+		}
+		start = -1
+		class = otherClass
+	}
+
+	for i, r := range runes {
+		c := classify(r)
+		switch {
+		case c == markClass && start >= 0:
+			// combining marks extend the current word
+		case c == midLetterClass && class == letterClass &&
+			i+1 < len(runes) && classify(runes[i+1]) == letterClass:
+			// keep apostrophes and periods between letters and digits
+		case (c == letterClass || c == katakanaClass) && c == class:
+			// continue the current word
+		case c == letterClass || c == katakanaClass || c == ideographClass:
+			flush(i)
+			start = i
+			class = c
+		default:
+			flush(i)
+		}
+	}
+	flush(len(runes))
+
+	return tokens
+}
@@ -0,0 +1,42 @@
+package lsh
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WhitespaceTokenizer(t *testing.T) {
+	assert.Equal(t, []string{"Hello", "world"}, NewWhitespaceTokenizer().Tokenize(" Hello,  world! "))
+}
+
+func Test_WordTokenizer(t *testing.T) {
+	tokenizer := NewWordTokenizer()
+
+	assert.Equal(t, []string{"Don't", "pay", "3.14", "for", "snake_case", "words"},
+		tokenizer.Tokenize("Don't pay $3.14 for snake_case words..."))
+	assert.Equal(t, []string{"東", "京", "は", "大", "き", "い", "テレビ", "で", "す"},
+		tokenizer.Tokenize("東京は大きいテレビです。"))
+	assert.Equal(t, []string{"cafe\u0301", "naïve"}, tokenizer.Tokenize("cafe\u0301, naïve"))
+	assert.Empty(t, tokenizer.Tokenize(" -- "))
+}
+
+func Test_RegexpTokenizer(t *testing.T) {
+	tokenizer := NewRegexpTokenizer(regexp.MustCompile(`[a-z]+`))
+	assert.Equal(t, []string{"abc", "de"}, tokenizer.Tokenize("abc12de"))
+}
+
+func Test_CodeTokenizer(t *testing.T) {
+	assert.Equal(t,
+		[]string{"if", "err", "!=", "nil", "{", "return", "fmt", ".", "Errorf", "(", `"bad \"%d\""`, ",", "x_1", "+", "0.5", ")", "}"},
+		NewCodeTokenizer().Tokenize(`if err != nil { return fmt.Errorf("bad \"%d\"", x_1+0.5) }`))
+}
+
+func Test_Shingle_Tokenizer(t *testing.T) {
+	shingles := Shingle([]string{"東京は大きい"}, WithTokenizer(NewWordTokenizer()), StopWordAnchors(false), ShingleWords(2))
+	assert.Equal(t, []string{"東 京", "京 は", "は 大", "大 き", "き い"}, shingles)
+
+	shingles = KShingle([]string{"x:=a+b"}, 3, WithTokenizer(NewCodeTokenizer()))
+	assert.Equal(t, []string{"x :", " :=", ":= ", "= a", " a ", "a +", " + ", "+ b"}, shingles)
+}